@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"boilerplate-service/config"
+	"boilerplate-service/pkg/crypto"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/mySqlExt"
+	"boilerplate-service/pkg/newRelicExt"
+	"boilerplate-service/pkg/rabbitMQExt"
+	"boilerplate-service/pkg/redisExt"
+	"boilerplate-service/pkg/util"
+	httputil "boilerplate-service/pkg/util/http"
+	"fmt"
+	"log"
+	"time"
+)
+
+// infra holds the clients shared by every serve command
+type infra struct {
+	config   *config.Config
+	secret   *config.Secret
+	logger   logger.ILogger
+	newRelic newRelicExt.INewRelicExt
+	db       mySqlExt.IMySqlExt
+	cache    redisExt.IRedisExt
+	redactor *util.Redactor
+	// encryptor is nil when SECURITY.CARD_KEYS is empty
+	encryptor crypto.IFieldEncryptor
+}
+
+// initInfra loads config and connects logger, New Relic, MySQL and Redis.
+//
+// It panics when a dependency can't be initialized.
+// Returns the clients and a func closing them in reverse order.
+func initInfra() (*infra, func()) {
+	// Init config
+	config, secret, err := config.LoadConfig(cfgFile, scrtFile)
+	if err != nil {
+		log.Fatalf("Unable to load configuration and secret: %v", err)
+	}
+
+	// Redaction of logs and New Relic attributes
+	redactor, err := newRedactor(config)
+	if err != nil {
+		log.Fatalf("Invalid redaction config: %v", err)
+	}
+
+	// Logger
+	loggerConfig := logger.Config{
+		Environment: config.Environment,
+		ServiceName: config.ServiceName,
+		Redactor:    redactor,
+	}
+	logger, err := logger.New(
+		loggerConfig,
+	)
+	if err != nil {
+		fmt.Printf("Unable to init logger, %v", err)
+		panic(err)
+	}
+
+	// New Relic
+	newRelicExtConfig := newRelicExt.Config{
+		Environment: config.Environment,
+		LicenseKey:  secret.NewRelicLicenseKey,
+		ServiceName: config.ServiceName,
+		Logger:      logger,
+		Redactor:    redactor,
+	}
+	newRelic, err := newRelicExt.New(newRelicExtConfig)
+	if err != nil {
+		fmt.Printf("Unable to init new relic, %v", err)
+		panic(err)
+	}
+
+	// MySQL Database
+	mysqlReplicas := make([]mySqlExt.Replica, 0, len(config.MySQLConfig.Replicas))
+	for _, replica := range config.MySQLConfig.Replicas {
+		mysqlReplicas = append(mysqlReplicas, mySqlExt.Replica{
+			Host: replica.Host,
+			Port: replica.Port,
+		})
+	}
+
+	mysqlExtConfig := mySqlExt.Config{
+		Host:         config.MySQLConfig.Host,
+		Port:         config.MySQLConfig.Port,
+		Username:     secret.MySQLSecret.Username,
+		Password:     secret.MySQLSecret.Password,
+		DBName:       secret.MySQLSecret.Database,
+		MaxIdleConns: config.MySQLConfig.MaxIdleConns,
+		MaxIdleTime:  config.MySQLConfig.MaxIdleTime,
+		MaxLifeTime:  config.MySQLConfig.MaxLifeTime,
+		MaxOpenConns: config.MySQLConfig.MaxOpenConns,
+
+		Replicas:                   mysqlReplicas,
+		ReplicaHealthCheckInterval: config.MySQLConfig.ReplicaHealthCheckInterval,
+		Logger:                     logger,
+	}
+
+	dbClient, err := mySqlExt.New(mysqlExtConfig)
+	if err != nil {
+		fmt.Printf("Unable to init mysql gateway, %v", err)
+		panic(err)
+	}
+
+	// Redis
+	cacheClient, err := redisExt.New(newRedisExtConfig(config, secret, config.RedisConfig.CacheDB))
+	if err != nil {
+		fmt.Printf("Unable to init redis cache, %v", err)
+		panic(err)
+	}
+
+	// Card data encryption
+	encryptor, err := newFieldEncryptor(secret)
+	if err != nil {
+		fmt.Printf("Unable to init card encryption, %v", err)
+		panic(err)
+	}
+	if encryptor != nil {
+		crypto.SetDefault(encryptor)
+	}
+
+	// Outbound HTTP, signed for the services verifying our REQUEST_SIGNING key
+	httputil.SetDefaultClient(newHttpClient(secret, logger, newRelic))
+
+	closeFn := func() {
+		cacheClient.Close()
+		dbClient.Close()
+		newRelic.Shutdown(10 * time.Second)
+		logger.Sync()
+	}
+
+	return &infra{
+		config:   config,
+		secret:   secret,
+		logger:   logger,
+		newRelic: newRelic,
+		db:       dbClient,
+		cache:    cacheClient,
+		redactor: redactor,
+
+		encryptor: encryptor,
+	}, closeFn
+}
+
+// newRedactor builds the redactor from the default rules and the REDACTION config.
+//
+// Returns an error when a pattern doesn't compile.
+func newRedactor(config *config.Config) (*util.Redactor, error) {
+	redactionConfig := util.DefaultRedactionConfig()
+	redactionConfig.Headers = append(redactionConfig.Headers, config.RedactionConfig.Headers...)
+	redactionConfig.Fields = append(redactionConfig.Fields, config.RedactionConfig.Fields...)
+	redactionConfig.JSONPaths = append(redactionConfig.JSONPaths, config.RedactionConfig.JSONPaths...)
+	for _, pattern := range config.RedactionConfig.Patterns {
+		redactionConfig.Patterns = append(redactionConfig.Patterns, util.RedactionPattern{
+			Name:  pattern.Name,
+			Regex: pattern.Regex,
+			Mask:  pattern.Mask,
+		})
+	}
+
+	return util.NewRedactor(redactionConfig)
+}
+
+// newFieldEncryptor builds the card data encryptor from SECURITY secrets.
+//
+// Returns nil without error when no card key is configured.
+func newFieldEncryptor(secret *config.Secret) (crypto.IFieldEncryptor, error) {
+	security := secret.SecuritySecret
+	if len(security.CardKeys) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]crypto.Key, 0, len(security.CardKeys))
+	for _, key := range security.CardKeys {
+		keySecret, err := crypto.ParseKey(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("card key %q: %w", key.KeyId, err)
+		}
+
+		keys = append(keys, crypto.Key{
+			Id:     key.KeyId,
+			Secret: keySecret,
+		})
+	}
+
+	blindIndexKey, err := crypto.ParseKey(security.CardBlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("card blind index key: %w", err)
+	}
+
+	cryptoConfig := crypto.Config{
+		ActiveKeyId:   security.CardActiveKeyId,
+		Keys:          keys,
+		BlindIndexKey: blindIndexKey,
+	}
+	if security.CardSecretKey != "" {
+		if cryptoConfig.LegacyKey, err = crypto.ParseKey(security.CardSecretKey); err != nil {
+			return nil, fmt.Errorf("card secret key: %w", err)
+		}
+		if cryptoConfig.LegacyIV, err = crypto.ParseKey(security.CardIV); err != nil {
+			return nil, fmt.Errorf("card iv: %w", err)
+		}
+	}
+
+	return crypto.New(cryptoConfig)
+}
+
+// newHttpClient builds the default outbound client, signing every request when
+// REQUEST_SIGNING.KEY_ID and SECRET are set.
+//
+// Returns httputil.IClient.
+func newHttpClient(secret *config.Secret, logger logger.ILogger, newRelic newRelicExt.INewRelicExt) httputil.IClient {
+	clientConfig := httputil.Config{
+		MaxRetries: 2,
+		Logger:     logger,
+		NewRelic:   newRelic,
+	}
+
+	signing := secret.RequestSigningSecret
+	if signing.KeyId != "" && signing.Secret != "" {
+		clientConfig.Signer = httputil.NewSigner(signing.KeyId, signing.Secret)
+	}
+
+	return httputil.New(clientConfig)
+}
+
+// newRedisExtConfig builds the redis client config for the given logical db.
+//
+// Returns redisExt.Config.
+func newRedisExtConfig(config *config.Config, secret *config.Secret, db int) redisExt.Config {
+	return redisExt.Config{
+		Mode:       config.RedisConfig.Mode,
+		Host:       config.RedisConfig.Host,
+		Port:       config.RedisConfig.Port,
+		Addrs:      config.RedisConfig.Addrs,
+		MasterName: config.RedisConfig.MasterName,
+		DB:         db,
+
+		Username:         secret.RedisSecret.Username,
+		Password:         secret.RedisSecret.Password,
+		SentinelUsername: secret.RedisSecret.SentinelUsername,
+		SentinelPassword: secret.RedisSecret.SentinelPassword,
+
+		TLSEnabled:            config.RedisConfig.TLSEnabled,
+		TLSInsecureSkipVerify: config.RedisConfig.TLSInsecureSkipVerify,
+
+		PoolSize:     config.RedisConfig.PoolSize,
+		MinIdleConns: config.RedisConfig.MinIdleConns,
+		DialTimeout:  config.RedisConfig.DialTimeout,
+		ReadTimeout:  config.RedisConfig.ReadTimeout,
+		WriteTimeout: config.RedisConfig.WriteTimeout,
+	}
+}
+
+// initIdempotencyRedis connects a redis client on REDIS.IDEMPOTENCY_DB.
+//
+// It panics when the connection can't be established.
+func initIdempotencyRedis(infra *infra) redisExt.IRedisExt {
+	idempotencyClient, err := redisExt.New(newRedisExtConfig(infra.config, infra.secret, infra.config.RedisConfig.IdempotencyDB))
+	if err != nil {
+		fmt.Printf("Unable to init redis idempotency, %v", err)
+		panic(err)
+	}
+
+	return idempotencyClient
+}
+
+// initRabbitMQ connects to RabbitMQ with the loaded config.
+//
+// It panics when the connection can't be established.
+func initRabbitMQ(infra *infra) rabbitMQExt.IRabbitMQExt {
+	rabbitMQExtConfig := rabbitMQExt.Config{
+		Host:           infra.config.RabbitMQConfig.Host,
+		Port:           infra.config.RabbitMQConfig.Port,
+		VHost:          infra.config.RabbitMQConfig.VHost,
+		Username:       infra.secret.RabbitMQSecret.Username,
+		Password:       infra.secret.RabbitMQSecret.Password,
+		ConnectionName: infra.config.ServiceName,
+		Logger:         infra.logger,
+		NewRelic:       infra.newRelic,
+	}
+
+	rabbitMQ, err := rabbitMQExt.New(rabbitMQExtConfig)
+	if err != nil {
+		fmt.Printf("Unable to init rabbitmq, %v", err)
+		panic(err)
+	}
+
+	return rabbitMQ
+}
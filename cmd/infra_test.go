@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"boilerplate-service/config"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestNewFieldEncryptor(t *testing.T) {
+	key := strings.Repeat("k", 32)
+	blindIndexKey := "base64:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+
+	tests := []struct {
+		name     string
+		security config.SecuritySecret
+		wantNil  bool
+		wantErr  bool
+	}{
+		{
+			name:    "No Card Keys",
+			wantNil: true,
+		},
+		{
+			name: "Base64 And Hex Keys",
+			security: config.SecuritySecret{
+				CardActiveKeyId: "2024-06",
+				CardKeys: []config.CardKeySecret{
+					{KeyId: "2023-01", Secret: "hex:" + hex.EncodeToString([]byte(key))},
+					{KeyId: "2024-06", Secret: "base64:" + base64.StdEncoding.EncodeToString([]byte(key))},
+				},
+				CardBlindIndexKey: blindIndexKey,
+			},
+		},
+		{
+			name: "Raw Legacy Key And IV",
+			security: config.SecuritySecret{
+				CardSecretKey:     strings.Repeat("l", 32),
+				CardIV:            strings.Repeat("i", 16),
+				CardKeys:          []config.CardKeySecret{{KeyId: "2024-06", Secret: key}},
+				CardBlindIndexKey: blindIndexKey,
+			},
+		},
+		{
+			name: "Undecodable Key",
+			security: config.SecuritySecret{
+				CardKeys: []config.CardKeySecret{{KeyId: "2024-06", Secret: "base64:***"}},
+			},
+			wantErr: true,
+		},
+		{
+			// A 32 character base64 string is 24 bytes once decoded, not an AES-256 key
+			name: "Base64 Key Of The Wrong Size",
+			security: config.SecuritySecret{
+				CardKeys: []config.CardKeySecret{{KeyId: "2024-06", Secret: "base64:" + base64.StdEncoding.EncodeToString([]byte(key[:24]))}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Legacy Key Of The Wrong Size",
+			security: config.SecuritySecret{
+				CardSecretKey: "legacy key",
+				CardIV:        strings.Repeat("i", 16),
+				CardKeys:      []config.CardKeySecret{{KeyId: "2024-06", Secret: key}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Short Blind Index Key",
+			security: config.SecuritySecret{
+				CardKeys:          []config.CardKeySecret{{KeyId: "2024-06", Secret: key}},
+				CardBlindIndexKey: "blind-index-key",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptor, err := newFieldEncryptor(&config.Secret{SecuritySecret: tt.security})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newFieldEncryptor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if (encryptor == nil) != tt.wantNil {
+				t.Fatalf("newFieldEncryptor() = %v, wantNil %v", encryptor, tt.wantNil)
+			}
+			if encryptor == nil {
+				return
+			}
+
+			ciphertext, err := encryptor.Encrypt([]byte("4111111111111111"))
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if plaintext, err := encryptor.Decrypt(ciphertext); err != nil || string(plaintext) != "4111111111111111" {
+				t.Errorf("Decrypt() = %q, %v", plaintext, err)
+			}
+		})
+	}
+}
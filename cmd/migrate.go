@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"boilerplate-service/config"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/migrationExt"
+	"boilerplate-service/pkg/mySqlExt"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateGotoCmd)
+	migrateCmd.AddCommand(migrateCreateCmd)
+
+	rootCmd.AddCommand(migrateCmd)
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Run database migrations",
+	Long:  `Apply or roll back versioned .sql migration files from the --migration directory`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigration(func(ctx context.Context, migrator migrationExt.IMigrationExt) error {
+			return migrator.Up(ctx)
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down N",
+	Short: "Roll back the last N applied migrations",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatalf("Invalid number of steps %q: %v", args[0], err)
+		}
+
+		runMigration(func(ctx context.Context, migrator migrationExt.IMigrationExt) error {
+			return migrator.Down(ctx, steps)
+		})
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto VERSION",
+	Short: "Migrate up or down to the given version",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid version %q: %v", args[0], err)
+		}
+
+		runMigration(func(ctx context.Context, migrator migrationExt.IMigrationExt) error {
+			return migrator.Goto(ctx, version)
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigration(func(ctx context.Context, migrator migrationExt.IMigrationExt) error {
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+			for _, status := range statuses {
+				state, appliedAt := "pending", ""
+				if status.Applied {
+					state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+				}
+				if status.Modified {
+					state = "modified"
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create a new empty up/down migration pair",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		upPath, downPath, err := migrationExt.Create(migrationFile, args[0])
+		if err != nil {
+			log.Fatalf("Unable to create migration: %v", err)
+		}
+
+		fmt.Println(upPath)
+		fmt.Println(downPath)
+	},
+}
+
+// runMigration loads configuration, connects the migrator and runs fn against it.
+func runMigration(fn func(ctx context.Context, migrator migrationExt.IMigrationExt) error) {
+	// Init config
+	config, secret, err := config.LoadConfig(cfgFile, scrtFile)
+	if err != nil {
+		log.Fatalf("Unable to load configuration and secret: %v", err)
+	}
+
+	// Logger
+	logger, err := logger.New(logger.Config{
+		Environment: config.Environment,
+		ServiceName: config.ServiceName,
+	})
+	if err != nil {
+		log.Fatalf("Unable to init logger, %v", err)
+	}
+	defer logger.Sync()
+
+	migrator, err := migrationExt.New(migrationExt.Config{
+		MySQL: mySqlExt.Config{
+			Host:     config.MySQLConfig.Host,
+			Port:     config.MySQLConfig.Port,
+			Username: secret.MySQLSecret.Username,
+			Password: secret.MySQLSecret.Password,
+			DBName:   secret.MySQLSecret.Database,
+		},
+		Dir:    migrationFile,
+		Logger: logger,
+	})
+	if err != nil {
+		log.Fatalf("Unable to init migration, %v", err)
+	}
+	defer migrator.Close()
+
+	if err := fn(context.Background(), migrator); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
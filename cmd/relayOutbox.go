@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"boilerplate-service/pkg/outbox"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func init() {
+	rootCmd.AddCommand(relayOutboxCmd)
+}
+
+var relayOutboxCmd = &cobra.Command{
+	Use:   "relayOutbox",
+	Short: "Start outbox relay",
+	Long:  `Publish events recorded in the outbox table to RabbitMQ`,
+	Run: func(cmd *cobra.Command, args []string) {
+		infra, closeInfra := initInfra()
+		defer closeInfra()
+
+		config, logger := infra.config, infra.logger
+
+		// RabbitMQ
+		rabbitMQ := initRabbitMQ(infra)
+		defer rabbitMQ.Close()
+
+		outboxConfig := outbox.Config{
+			PollInterval: time.Duration(config.OutboxConfig.PollInterval) * time.Second,
+			BatchSize:    config.OutboxConfig.BatchSize,
+			MaxAttempts:  config.OutboxConfig.MaxAttempts,
+			RetryDelay:   time.Duration(config.OutboxConfig.RetryDelay) * time.Second,
+			Retention:    time.Duration(config.OutboxConfig.RetentionHours) * time.Hour,
+			Logger:       logger,
+		}
+		relay := outbox.New(infra.db, rabbitMQ, outboxConfig)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			if err := relay.Relay(ctx); err != nil {
+				logger.Error(ctx, "Outbox relay failed", zap.Error(err))
+			}
+		}()
+
+		// Set up signal capturing
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		// Block until we receive our signal.
+		select {
+		case <-quit:
+		case <-stopped:
+		}
+
+		// The batch in flight finishes before Relay returns
+		cancel()
+
+		select {
+		case <-stopped:
+			log.Println("Outbox relay gracefully stopped")
+		case <-time.After(15 * time.Second):
+			log.Println("Outbox relay shutdown timed out")
+		}
+	},
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"boilerplate-service/config"
+	"boilerplate-service/pkg/keyRotation"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	rotateKeysTables  []string
+	rotateKeysRestart bool
+	rotateKeysDryRun  bool
+)
+
+func init() {
+	rotateKeysCmd.Flags().StringSliceVar(&rotateKeysTables, "table", nil, "only rotate these configured tables")
+	rotateKeysCmd.Flags().BoolVar(&rotateKeysRestart, "restart", false, "ignore the stored checkpoint and start from the first row")
+	rotateKeysCmd.Flags().BoolVar(&rotateKeysDryRun, "dry-run", false, "count the values to rotate without writing")
+
+	rootCmd.AddCommand(rotateKeysCmd)
+}
+
+var rotateKeysCmd = &cobra.Command{
+	Use:   "rotateKeys",
+	Short: "Re-encrypt stored data with the active key",
+	Long:  `Walk the KEY_ROTATION tables in batches and re-encrypt every value not sealed with SECURITY.CARD_ACTIVE_KEY_ID, resuming from the last checkpoint`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		infra, closeInfra := initInfra()
+		defer closeInfra()
+
+		config, logger := infra.config, infra.logger
+
+		if infra.encryptor == nil {
+			log.Fatalf("SECURITY.CARD_KEYS is empty, nothing to rotate to")
+		}
+
+		tables, err := keyRotationTables(config.KeyRotationConfig.Tables, rotateKeysTables)
+		if err != nil {
+			log.Fatalf("Invalid --table: %v", err)
+		}
+
+		rotation := keyRotation.New(infra.db, infra.encryptor, keyRotation.Config{
+			BatchSize: config.KeyRotationConfig.BatchSize,
+			Throttle:  time.Duration(config.KeyRotationConfig.Throttle) * time.Millisecond,
+			Restart:   rotateKeysRestart,
+			DryRun:    rotateKeysDryRun,
+			Logger:    logger,
+		})
+
+		// The batch in flight is committed or rolled back as a whole, stopping is always safe
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		for _, table := range tables {
+			if _, err := rotation.Rotate(ctx, table); err != nil {
+				logger.Error(ctx, "Key rotation failed", zap.String("table", table.Name), zap.Error(err))
+
+				// os.Exit skips the deferred calls, close first so the logs are flushed
+				stop()
+				closeInfra()
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// keyRotationTables maps the KEY_ROTATION tables, keeping only the given names when any.
+//
+// Returns an error when a name is not configured.
+func keyRotationTables(configured []config.KeyRotationTableConfig, names []string) ([]keyRotation.Table, error) {
+	tables := make([]keyRotation.Table, 0, len(configured))
+	byName := make(map[string]keyRotation.Table, len(configured))
+	for _, table := range configured {
+		t := keyRotation.Table{Name: table.Name, PrimaryKey: table.PrimaryKey, Columns: table.Columns}
+		tables = append(tables, t)
+		byName[table.Name] = t
+	}
+
+	if len(names) == 0 {
+		return tables, nil
+	}
+
+	selected := make([]keyRotation.Table, 0, len(names))
+	for _, name := range names {
+		table, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("table %q is not in KEY_ROTATION.TABLES", name)
+		}
+		selected = append(selected, table)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"boilerplate-service/port/consumer"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func init() {
+	rootCmd.AddCommand(serveConsumerCmd)
+}
+
+var serveConsumerCmd = &cobra.Command{
+	Use:   "serveConsumer",
+	Short: "Start RabbitMQ consumers",
+	Long:  `Start Boilerplate RabbitMQ consumer workers`,
+	Run: func(cmd *cobra.Command, args []string) {
+		infra, closeInfra := initInfra()
+		defer closeInfra()
+
+		config, logger := infra.config, infra.logger
+
+		// RabbitMQ
+		rabbitMQ := initRabbitMQ(infra)
+		defer rabbitMQ.Close()
+
+		// Init repository, services and consumer handlers the same way as serveHttp
+		// e.g. database, external/internal services repository, etc.
+
+		// Init consumer routes
+		routes := consumer.ConsumerRoute(
+			config,
+		)
+		if len(routes) == 0 {
+			logger.Warn(context.Background(), "No RabbitMQ consumer registered")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, route := range routes {
+			wg.Add(1)
+			go func(route consumer.Route) {
+				defer wg.Done()
+
+				logger.Info(
+					ctx,
+					"Starting RabbitMQ consumer",
+					zap.String("queue", route.Config.Queue),
+					zap.Int("concurrency", route.Config.Concurrency),
+				)
+				if err := rabbitMQ.Consume(ctx, route.Config, route.Handler); err != nil {
+					logger.Error(ctx, "RabbitMQ consumer failed", zap.String("queue", route.Config.Queue), zap.Error(err))
+				}
+			}(route)
+		}
+
+		// Set up signal capturing
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		// Block until we receive our signal.
+		<-quit
+
+		// Stop consuming, in-flight messages keep being handled and acked
+		cancel()
+
+		drained := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(drained)
+		}()
+
+		// Create a deadline to wait for.
+		select {
+		case <-drained:
+			log.Println("Consumer gracefully stopped")
+		case <-time.After(15 * time.Second):
+			log.Println("Consumer shutdown timed out, unacked messages will be redelivered")
+		}
+	},
+}
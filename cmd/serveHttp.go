@@ -1,14 +1,9 @@
 package cmd
 
 import (
-	"boilerplate-service/config"
-	"boilerplate-service/pkg/logger"
-	"boilerplate-service/pkg/mySqlExt"
-	"boilerplate-service/pkg/newRelicExt"
-	"boilerplate-service/pkg/redisExt"
+	"boilerplate-service/pkg/jwtExt"
 	"boilerplate-service/port/http"
 	"context"
-	"fmt"
 	"log"
 	netHttp "net/http"
 	"os"
@@ -19,6 +14,7 @@ import (
 	healthCheckRepo "boilerplate-service/internal/repository/healthCheck"
 	healthCheckSvc "boilerplate-service/internal/service/v1/healthCheck"
 	v1HealthCheckController "boilerplate-service/port/http/controller/v1/healthCheck"
+	customMiddleware "boilerplate-service/port/http/middleware"
 
 	"github.com/spf13/cobra"
 )
@@ -32,73 +28,15 @@ var serveHttpCmd = &cobra.Command{
 	Short: "Start HTTP server",
 	Long:  `Start Boilerplate HTTP server`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Init config
-		config, secret, err := config.LoadConfig(cfgFile, scrtFile)
-		if err != nil {
-			log.Fatalf("Unable to load configuration and secret: %v", err)
-		}
-
-		// Logger
-		loggerConfig := logger.Config{
-			Environment: config.Environment,
-			ServiceName: config.ServiceName,
-		}
-		logger, err := logger.New(
-			loggerConfig,
-		)
-		if err != nil {
-			fmt.Printf("Unable to init logger, %v", err)
-			panic(err)
-		}
-		defer logger.Sync()
-
-		// New Relic
-		newRelicExtConfig := newRelicExt.Config{
-			Environment: config.Environment,
-			LicenseKey:  secret.NewRelicLicenseKey,
-			ServiceName: config.ServiceName,
-			Logger:      logger,
-		}
-		newRelic, err := newRelicExt.New(newRelicExtConfig)
-		if err != nil {
-			fmt.Printf("Unable to init new relic, %v", err)
-			panic(err)
-		}
+		infra, closeInfra := initInfra()
+		defer closeInfra()
 
-		defer newRelic.Shutdown(10 * time.Second)
-
-		// MySQL Database
-		mysqlExtConfig := mySqlExt.Config{
-			Host:         config.MySQLConfig.Host,
-			Port:         config.MySQLConfig.Port,
-			Username:     secret.MySQLSecret.Username,
-			Password:     secret.MySQLSecret.Password,
-			DBName:       secret.MySQLSecret.Database,
-			MaxIdleConns: config.MySQLConfig.MaxIdleConns,
-			MaxIdleTime:  config.MySQLConfig.MaxOpenConns,
-			MaxLifeTime:  config.MySQLConfig.MaxLifeTime,
-			MaxOpenConns: config.MySQLConfig.MaxOpenConns,
-		}
+		config, logger, newRelic := infra.config, infra.logger, infra.newRelic
+		dbClient, cacheClient := infra.db, infra.cache
 
-		dbClient, err := mySqlExt.New(mysqlExtConfig)
-		if err != nil {
-			fmt.Printf("Unable to init mysql gateway, %v", err)
-			panic(err)
-		}
-		defer dbClient.Close()
-
-		// Redis
-		cacheClient, err := redisExt.New(
-			config.RedisConfig.Host,
-			config.RedisConfig.Port,
-			secret.RedisSecret.Password,
-			config.RedisConfig.CacheDB,
-		)
-		if err != nil {
-			fmt.Printf("Unable to init redis cache, %v", err)
-			panic(err)
-		}
-		defer cacheClient.Close()
+		// Redis for idempotency keys, kept apart from the cache db
+		idempotencyClient := initIdempotencyRedis(infra)
+		defer idempotencyClient.Close()
 
 		// todo Validator will used later when standardize validation request & response message done
 		// validate := validatorExt.New()
@@ -122,10 +60,56 @@ var serveHttpCmd = &cobra.Command{
 			healthCheckService,
 		)
 
+		// Rate limiter counters live in the cache db
+		rateLimiter, err := customMiddleware.NewRateLimiter(cacheClient, logger)
+		if err != nil {
+			log.Fatalf("Unable to init rate limiter: %v", err)
+		}
+
+		// JWT verification keys
+		jwtExtConfig := jwtExt.Config{
+			HMACKey:             infra.secret.JWTSecret.HMACKey,
+			JWKSFile:            config.JWTConfig.JWKSFile,
+			JWKSRefreshInterval: time.Duration(config.JWTConfig.JWKSRefreshInterval) * time.Second,
+			Issuer:              config.JWTConfig.Issuer,
+			Audience:            config.JWTConfig.Audience,
+			Leeway:              time.Duration(config.JWTConfig.Leeway) * time.Second,
+			Logger:              logger,
+		}
+		jwt, err := jwtExt.New(jwtExtConfig)
+		if err != nil {
+			log.Fatalf("Unable to init jwt: %v", err)
+		}
+		defer jwt.Close()
+
+		// Signed service to service calls, nonces live in the cache db
+		trustedKeys := map[string]string{}
+		for _, key := range infra.secret.RequestSigningSecret.TrustedKeys {
+			trustedKeys[key.KeyId] = key.Secret
+		}
+		signatureVerifier := customMiddleware.NewSignatureVerifier(cacheClient, logger, customMiddleware.SignatureConfig{
+			Keys:    trustedKeys,
+			MaxSkew: time.Duration(config.RequestSigningConfig.MaxSkew) * time.Second,
+		})
+
+		// Request and response logging
+		loggerConfig := customMiddleware.LoggerConfig{
+			MaxRequestBodySize:  config.HTTPLogConfig.MaxRequestBodySize,
+			MaxResponseBodySize: config.HTTPLogConfig.MaxResponseBodySize,
+			ContentTypes:        config.HTTPLogConfig.ContentTypes,
+			SkipBodyPaths:       config.HTTPLogConfig.SkipBodyPaths,
+			Redactor:            infra.redactor,
+		}
+
 		// Init router
 		r := http.HttpRoute(
 			newRelic,
 			logger,
+			loggerConfig,
+			idempotencyClient,
+			rateLimiter,
+			jwt,
+			signatureVerifier,
 			healthCheckController,
 		)
 
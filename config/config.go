@@ -14,6 +14,13 @@ type Config struct {
 	MySQLConfig    MySQLConfig    `mapstructure:"DATABASE"`
 	RedisConfig    RedisConfig    `mapstructure:"REDIS"`
 	RabbitMQConfig RabbitMQConfig `mapstructure:"RABBITMQ"`
+	OutboxConfig   OutboxConfig   `mapstructure:"OUTBOX"`
+	JWTConfig      JWTConfig      `mapstructure:"JWT"`
+
+	RequestSigningConfig RequestSigningConfig `mapstructure:"REQUEST_SIGNING"`
+	KeyRotationConfig    KeyRotationConfig    `mapstructure:"KEY_ROTATION"`
+	RedactionConfig      RedactionConfig      `mapstructure:"REDACTION"`
+	HTTPLogConfig        HTTPLogConfig        `mapstructure:"HTTP_LOG"`
 }
 
 type Secret struct {
@@ -21,6 +28,9 @@ type Secret struct {
 	RedisSecret    RedisSecret    `mapstructure:"REDIS"`
 	RabbitMQSecret RabbitMQSecret `mapstructure:"RABBITMQ"`
 	SecuritySecret SecuritySecret `mapstructure:"SECURITY"`
+	JWTSecret      JWTSecret      `mapstructure:"JWT"`
+
+	RequestSigningSecret RequestSigningSecret `mapstructure:"REQUEST_SIGNING"`
 
 	NewRelicLicenseKey string `mapstructure:"NEW_RELIC_LICENSE_KEY"`
 }
@@ -33,6 +43,14 @@ type MySQLConfig struct {
 	MaxOpenConns int    `mapstructure:"MAX_OPEN_CONNS"`
 	MaxIdleTime  int    `mapstructure:"MAX_IDLE_TIME"`
 	MaxLifeTime  int    `mapstructure:"MAX_LIFE_TIME"`
+
+	Replicas                   []MySQLReplicaConfig `mapstructure:"REPLICAS"`
+	ReplicaHealthCheckInterval int                  `mapstructure:"REPLICA_HEALTH_CHECK_INTERVAL"`
+}
+
+type MySQLReplicaConfig struct {
+	Host string `mapstructure:"HOST"`
+	Port string `mapstructure:"PORT"`
 }
 
 type MySQLSecret struct {
@@ -42,18 +60,119 @@ type MySQLSecret struct {
 }
 
 type RedisConfig struct {
-	Host    string `mapstructure:"HOST"`
-	Port    string `mapstructure:"PORT"`
-	CacheDB int    `mapstructure:"CACHE_DB"`
+	Mode       string   `mapstructure:"MODE"`
+	Host       string   `mapstructure:"HOST"`
+	Port       string   `mapstructure:"PORT"`
+	Addrs      []string `mapstructure:"ADDRS"`
+	MasterName string   `mapstructure:"MASTER_NAME"`
+	CacheDB    int      `mapstructure:"CACHE_DB"`
+
+	IdempotencyDB int `mapstructure:"IDEMPOTENCY_DB"`
+
+	TLSEnabled            bool `mapstructure:"TLS_ENABLED"`
+	TLSInsecureSkipVerify bool `mapstructure:"TLS_INSECURE_SKIP_VERIFY"`
+
+	PoolSize     int `mapstructure:"POOL_SIZE"`
+	MinIdleConns int `mapstructure:"MIN_IDLE_CONNS"`
+	DialTimeout  int `mapstructure:"DIAL_TIMEOUT"`
+	ReadTimeout  int `mapstructure:"READ_TIMEOUT"`
+	WriteTimeout int `mapstructure:"WRITE_TIMEOUT"`
 }
 
 type RedisSecret struct {
-	Password string `mapstructure:"PASSWORD"`
+	Username         string `mapstructure:"USERNAME"`
+	Password         string `mapstructure:"PASSWORD"`
+	SentinelUsername string `mapstructure:"SENTINEL_USERNAME"`
+	SentinelPassword string `mapstructure:"SENTINEL_PASSWORD"`
 }
 
 type RabbitMQConfig struct {
-	Host string `mapstructure:"HOST"`
-	Port string `mapstructure:"PORT"`
+	Host  string `mapstructure:"HOST"`
+	Port  string `mapstructure:"PORT"`
+	VHost string `mapstructure:"VHOST"`
+
+	Concurrency int                      `mapstructure:"CONCURRENCY"`
+	Prefetch    int                      `mapstructure:"PREFETCH"`
+	Consumers   []RabbitMQConsumerConfig `mapstructure:"CONSUMERS"`
+}
+
+type RabbitMQConsumerConfig struct {
+	Queue       string `mapstructure:"QUEUE"`
+	Concurrency int    `mapstructure:"CONCURRENCY"`
+	Prefetch    int    `mapstructure:"PREFETCH"`
+}
+
+type OutboxConfig struct {
+	PollInterval   int `mapstructure:"POLL_INTERVAL"`
+	BatchSize      int `mapstructure:"BATCH_SIZE"`
+	MaxAttempts    int `mapstructure:"MAX_ATTEMPTS"`
+	RetryDelay     int `mapstructure:"RETRY_DELAY"`
+	RetentionHours int `mapstructure:"RETENTION_HOURS"`
+}
+
+type JWTConfig struct {
+	Issuer              string   `mapstructure:"ISSUER"`
+	Audience            []string `mapstructure:"AUDIENCE"`
+	JWKSFile            string   `mapstructure:"JWKS_FILE"`
+	JWKSRefreshInterval int      `mapstructure:"JWKS_REFRESH_INTERVAL"`
+	Leeway              int      `mapstructure:"LEEWAY"`
+}
+
+type JWTSecret struct {
+	HMACKey string `mapstructure:"HMAC_KEY"`
+}
+
+type RequestSigningConfig struct {
+	MaxSkew int `mapstructure:"MAX_SKEW"`
+}
+
+type RequestSigningSecret struct {
+	// KeyId and Secret sign the outbound calls of the default httputil client
+	KeyId  string `mapstructure:"KEY_ID"`
+	Secret string `mapstructure:"SECRET"`
+	// TrustedKeys verify inbound calls from other services
+	TrustedKeys []RequestSigningKey `mapstructure:"TRUSTED_KEYS"`
+}
+
+type RequestSigningKey struct {
+	KeyId  string `mapstructure:"KEY_ID"`
+	Secret string `mapstructure:"SECRET"`
+}
+
+type KeyRotationConfig struct {
+	BatchSize int `mapstructure:"BATCH_SIZE"`
+	// Throttle is the pause between batches in milliseconds
+	Throttle int                      `mapstructure:"THROTTLE"`
+	Tables   []KeyRotationTableConfig `mapstructure:"TABLES"`
+}
+
+type KeyRotationTableConfig struct {
+	Name       string   `mapstructure:"NAME"`
+	PrimaryKey string   `mapstructure:"PRIMARY_KEY"`
+	Columns    []string `mapstructure:"COLUMNS"`
+}
+
+type HTTPLogConfig struct {
+	// MaxRequestBodySize and MaxResponseBodySize are in bytes
+	MaxRequestBodySize  int      `mapstructure:"MAX_REQUEST_BODY_SIZE"`
+	MaxResponseBodySize int      `mapstructure:"MAX_RESPONSE_BODY_SIZE"`
+	ContentTypes        []string `mapstructure:"CONTENT_TYPES"`
+	// SkipBodyPaths are path.Match patterns of routes whose bodies are never logged
+	SkipBodyPaths []string `mapstructure:"SKIP_BODY_PATHS"`
+}
+
+// RedactionConfig adds rules to util.DefaultRedactionConfig
+type RedactionConfig struct {
+	Headers   []string                 `mapstructure:"HEADERS"`
+	Fields    []string                 `mapstructure:"FIELDS"`
+	JSONPaths []string                 `mapstructure:"JSON_PATHS"`
+	Patterns  []RedactionPatternConfig `mapstructure:"PATTERNS"`
+}
+
+type RedactionPatternConfig struct {
+	Name  string `mapstructure:"NAME"`
+	Regex string `mapstructure:"REGEX"`
+	Mask  string `mapstructure:"MASK"`
 }
 
 type RabbitMQSecret struct {
@@ -62,8 +181,18 @@ type RabbitMQSecret struct {
 }
 
 type SecuritySecret struct {
+	// CardSecretKey and CardIV decrypt legacy AES-CBC card data
 	CardSecretKey string `mapstructure:"CARD_SECRET_KEY"`
 	CardIV        string `mapstructure:"CARD_IV"`
+
+	CardActiveKeyId   string          `mapstructure:"CARD_ACTIVE_KEY_ID"`
+	CardKeys          []CardKeySecret `mapstructure:"CARD_KEYS"`
+	CardBlindIndexKey string          `mapstructure:"CARD_BLIND_INDEX_KEY"`
+}
+
+type CardKeySecret struct {
+	KeyId  string `mapstructure:"KEY_ID"`
+	Secret string `mapstructure:"SECRET"`
 }
 
 func LoadConfig(configPath, secretPath string) (*Config, *Secret, error) {
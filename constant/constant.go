@@ -3,8 +3,8 @@ package constant
 type constantKey string
 
 const (
-	// ctxTraceIdKey is the context key for trace id
-	CtxTraceIdKey constantKey = "trace_id"
+	// CtxTraceContextKey is the context key for the traceContext.TraceContext of the request or message
+	CtxTraceContextKey constantKey = "trace_context"
 
 	EnvironmentDevelopment = "development"
 	EnvironmentLocal       = "local"
@@ -5,4 +5,14 @@ const (
 	CtxNewRelicTxnKey constantKey = "newrelic_txn"
 	// CtxSQLTableNameKey is the context key for sql table name
 	CtxSQLTableNameKey string = "table_name"
+	// CtxSQLTxKey is the context key for the active sql transaction
+	CtxSQLTxKey constantKey = "sql_tx"
+	// CtxSQLForcePrimaryKey is the context key to route reads to the primary database
+	CtxSQLForcePrimaryKey constantKey = "sql_force_primary"
+	// CtxPrincipalKey is the context key for the authenticated jwtExt.Principal
+	CtxPrincipalKey constantKey = "principal"
+	// CtxSubjectKey is the context key for the authenticated subject, logged with every entry
+	CtxSubjectKey constantKey = "subject"
+	// CtxAPIKeyIdKey is the context key for the key id of a request authenticated by its signature
+	CtxAPIKeyIdKey constantKey = "api_key_id"
 )
@@ -13,8 +13,10 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/newrelic/go-agent/v3/integrations/nrredis-v9 v1.0.0
+	github.com/rabbitmq/amqp091-go v1.9.0
 	github.com/redis/go-redis/v9 v9.4.0
 	github.com/spf13/viper v1.18.2
+	golang.org/x/sync v0.5.0
 )
 
 require (
@@ -34,7 +36,10 @@ require (
 	google.golang.org/protobuf v1.31.0 // indirect
 )
 
-require github.com/newrelic/go-agent/v3 v3.29.1
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.0
+	github.com/newrelic/go-agent/v3 v3.29.1
+)
 
 require (
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
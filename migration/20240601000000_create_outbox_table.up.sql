@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `outbox` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `message_id` VARCHAR(64) NOT NULL,
+  `exchange` VARCHAR(255) NOT NULL,
+  `routing_key` VARCHAR(255) NOT NULL,
+  `headers` JSON NULL,
+  `payload` LONGBLOB NOT NULL,
+  `status` VARCHAR(16) NOT NULL DEFAULT 'pending',
+  `attempts` INT NOT NULL DEFAULT 0,
+  `last_error` TEXT NULL,
+  `available_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  `created_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  `sent_at` DATETIME(6) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uq_outbox_message_id` (`message_id`),
+  KEY `idx_outbox_status_available_at` (`status`, `available_at`, `id`),
+  KEY `idx_outbox_status_sent_at` (`status`, `sent_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `inbox` (
+  `consumer` VARCHAR(255) NOT NULL,
+  `message_id` VARCHAR(64) NOT NULL,
+  `processed_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  PRIMARY KEY (`consumer`, `message_id`),
+  KEY `idx_inbox_processed_at` (`processed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `key_rotation_checkpoints` (
+  `table_name` VARCHAR(64) NOT NULL,
+  `target_key_id` VARCHAR(64) NOT NULL,
+  `last_key` VARCHAR(255) NOT NULL,
+  `updated_at` DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  PRIMARY KEY (`table_name`, `target_key_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
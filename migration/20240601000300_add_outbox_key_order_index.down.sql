@@ -0,0 +1 @@
+ALTER TABLE `outbox` DROP KEY `idx_outbox_status_exchange_routing_key`;
@@ -0,0 +1 @@
+ALTER TABLE `outbox` ADD KEY `idx_outbox_status_exchange_routing_key` (`status`, `exchange`, `routing_key`, `id`);
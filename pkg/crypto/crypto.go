@@ -0,0 +1,211 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownKey       = errors.New("ciphertext was encrypted with an unknown key id")
+	ErrMalformed        = errors.New("malformed ciphertext")
+	ErrNoLegacyKey      = errors.New("legacy ciphertext but no legacy key configured")
+	ErrNoBlindIndexKey  = errors.New("blind index key is not configured")
+	ErrNoEncryptionKey  = errors.New("at least one encryption key is required")
+	ErrUnknownActiveKey = errors.New("active key id is not among the configured keys")
+	ErrWeakBlindIndex   = errors.New("blind index key must be at least 32 bytes")
+)
+
+// ciphertextVersion prefixes AES-GCM ciphertexts: "v1:<key id>:<base64 nonce|sealed>".
+// Anything without it is taken as a legacy AES-CBC ciphertext.
+const (
+	ciphertextVersion = "v1"
+	// minBlindIndexKeySize is the HMAC-SHA256 output size, shorter keys weaken the index
+	minBlindIndexKeySize = 32
+)
+
+type IFieldEncryptor interface {
+	// Encrypt seals plaintext with the active key and a random nonce
+	Encrypt(plaintext []byte) (string, error)
+	// Decrypt opens a ciphertext of any configured key, or a legacy AES-CBC one
+	Decrypt(ciphertext string) ([]byte, error)
+	// BlindIndex returns a keyed hash of value, stable across key rotations, for equality lookups
+	BlindIndex(value string) (string, error)
+	// ActiveKeyId is the key id new ciphertexts are sealed with
+	ActiveKeyId() string
+	// KeyId returns the key id embedded in ciphertext, "" for legacy ciphertexts
+	KeyId(ciphertext string) (string, error)
+}
+
+type Key struct {
+	Id     string
+	Secret []byte
+}
+
+type Config struct {
+	// ActiveKeyId encrypts new values, defaults to the last of Keys
+	ActiveKeyId string
+	// Keys are AES-256 keys by id, every key still present in stored data must be listed
+	Keys []Key
+
+	// LegacyKey and LegacyIV decrypt values written before AES-GCM, with AES-CBC and PKCS#7 padding
+	LegacyKey []byte
+	LegacyIV  []byte
+
+	// BlindIndexKey is the HMAC-SHA256 key of BlindIndex, at least 32 bytes. It must never rotate with Keys.
+	BlindIndexKey []byte
+}
+
+type fieldEncryptor struct {
+	active     string
+	aeads      map[string]cipher.AEAD
+	legacy     cipher.Block
+	legacyIV   []byte
+	blindIndex []byte
+}
+
+func New(config Config) (IFieldEncryptor, error) {
+	if len(config.Keys) == 0 {
+		return nil, ErrNoEncryptionKey
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(config.Keys))
+	for _, key := range config.Keys {
+		if key.Id == "" || strings.Contains(key.Id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", key.Id)
+		}
+
+		if len(key.Secret) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes for AES-256, got %d", key.Id, len(key.Secret))
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads[key.Id] = aead
+	}
+
+	active := config.ActiveKeyId
+	if active == "" {
+		active = config.Keys[len(config.Keys)-1].Id
+	}
+	if _, ok := aeads[active]; !ok {
+		return nil, ErrUnknownActiveKey
+	}
+
+	if len(config.BlindIndexKey) > 0 && len(config.BlindIndexKey) < minBlindIndexKeySize {
+		return nil, ErrWeakBlindIndex
+	}
+
+	f := &fieldEncryptor{
+		active:     active,
+		aeads:      aeads,
+		blindIndex: config.BlindIndexKey,
+	}
+
+	if len(config.LegacyKey) > 0 {
+		if !isAESKeySize(len(config.LegacyKey)) {
+			return nil, fmt.Errorf("legacy key must be 16, 24 or 32 bytes, got %d", len(config.LegacyKey))
+		}
+
+		block, err := aes.NewCipher(config.LegacyKey)
+		if err != nil {
+			return nil, fmt.Errorf("legacy key: %w", err)
+		}
+
+		if len(config.LegacyIV) != aes.BlockSize {
+			return nil, fmt.Errorf("legacy iv must be %d bytes, got %d", aes.BlockSize, len(config.LegacyIV))
+		}
+
+		f.legacy = block
+		f.legacyIV = config.LegacyIV
+	}
+
+	return f, nil
+}
+
+func (f *fieldEncryptor) ActiveKeyId() string {
+	return f.active
+}
+
+func (f *fieldEncryptor) Encrypt(plaintext []byte) (string, error) {
+	aead := f.aeads[f.active]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	// The header is authenticated so a ciphertext can't be relabelled with another key id
+	header := ciphertextVersion + ":" + f.active
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(header))
+
+	return header + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (f *fieldEncryptor) Decrypt(ciphertext string) ([]byte, error) {
+	keyId, payload, ok := parseCiphertext(ciphertext)
+	if !ok {
+		return f.decryptLegacy(ciphertext)
+	}
+
+	aead, ok := f.aeads[keyId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyId)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformed
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(ciphertextVersion+":"+keyId))
+}
+
+func (f *fieldEncryptor) KeyId(ciphertext string) (string, error) {
+	keyId, _, ok := parseCiphertext(ciphertext)
+	if !ok {
+		return "", nil
+	}
+
+	if _, known := f.aeads[keyId]; !known {
+		return keyId, fmt.Errorf("%w: %q", ErrUnknownKey, keyId)
+	}
+
+	return keyId, nil
+}
+
+func (f *fieldEncryptor) BlindIndex(value string) (string, error) {
+	if len(f.blindIndex) == 0 {
+		return "", ErrNoBlindIndexKey
+	}
+
+	mac := hmac.New(sha256.New, f.blindIndex)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func parseCiphertext(ciphertext string) (keyId, payload string, ok bool) {
+	version, rest, found := strings.Cut(ciphertext, ":")
+	if !found || version != ciphertextVersion {
+		return "", "", false
+	}
+
+	keyId, payload, found = strings.Cut(rest, ":")
+	return keyId, payload, found
+}
@@ -0,0 +1,186 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	stdCipher "crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"boilerplate-service/pkg/crypto"
+)
+
+var (
+	oldKey    = crypto.Key{Id: "2023-01", Secret: bytes.Repeat([]byte{1}, 32)}
+	activeKey = crypto.Key{Id: "2024-06", Secret: bytes.Repeat([]byte{2}, 32)}
+	legacyKey = bytes.Repeat([]byte{3}, 32)
+	legacyIV  = bytes.Repeat([]byte{4}, 16)
+
+	// errAny expects an error without a sentinel to match
+	errAny = errors.New("any error")
+)
+
+func newEncryptor(t *testing.T, keys ...crypto.Key) crypto.IFieldEncryptor {
+	encryptor, err := crypto.New(crypto.Config{
+		Keys:          keys,
+		LegacyKey:     legacyKey,
+		LegacyIV:      legacyIV,
+		BlindIndexKey: bytes.Repeat([]byte{5}, 32),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return encryptor
+}
+
+func legacyEncrypt(plaintext string) string {
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, _ := aes.NewCipher(legacyKey)
+	stdCipher.NewCBCEncrypter(block, legacyIV).CryptBlocks(padded, padded)
+	return base64.StdEncoding.EncodeToString(padded)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  crypto.Config
+		wantErr error
+	}{
+		{
+			name:   "Valid",
+			config: crypto.Config{Keys: []crypto.Key{activeKey}, LegacyKey: legacyKey, LegacyIV: legacyIV, BlindIndexKey: bytes.Repeat([]byte{5}, 32)},
+		},
+		{
+			name:    "No Key",
+			config:  crypto.Config{},
+			wantErr: crypto.ErrNoEncryptionKey,
+		},
+		{
+			name:    "Short Key",
+			config:  crypto.Config{Keys: []crypto.Key{{Id: "short", Secret: bytes.Repeat([]byte{1}, 16)}}},
+			wantErr: errAny,
+		},
+		{
+			name:    "Unknown Active Key",
+			config:  crypto.Config{Keys: []crypto.Key{activeKey}, ActiveKeyId: "missing"},
+			wantErr: crypto.ErrUnknownActiveKey,
+		},
+		{
+			name:    "Legacy Key Of The Wrong Size",
+			config:  crypto.Config{Keys: []crypto.Key{activeKey}, LegacyKey: bytes.Repeat([]byte{3}, 20), LegacyIV: legacyIV},
+			wantErr: errAny,
+		},
+		{
+			name:    "Legacy IV Of The Wrong Size",
+			config:  crypto.Config{Keys: []crypto.Key{activeKey}, LegacyKey: legacyKey, LegacyIV: legacyIV[:8]},
+			wantErr: errAny,
+		},
+		{
+			name:    "Short Blind Index Key",
+			config:  crypto.Config{Keys: []crypto.Key{activeKey}, BlindIndexKey: []byte("blind-index-key")},
+			wantErr: crypto.ErrWeakBlindIndex,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := crypto.New(tt.config)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("New() error = %v", err)
+			case tt.wantErr == errAny && err == nil:
+				t.Fatal("New() error = nil, want an error")
+			case tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldEncryptor(t *testing.T) {
+	before := newEncryptor(t, oldKey)
+	after := newEncryptor(t, oldKey, activeKey)
+
+	oldCiphertext, _ := before.Encrypt([]byte("4111111111111111"))
+	newCiphertext, _ := after.Encrypt([]byte("4111111111111111"))
+	again, _ := after.Encrypt([]byte("4111111111111111"))
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		want       string
+		wantKeyId  string
+		wantErr    error
+	}{
+		{
+			name:       "Active Key",
+			ciphertext: newCiphertext,
+			want:       "4111111111111111",
+			wantKeyId:  "2024-06",
+		},
+		{
+			name:       "Rotated Out Key",
+			ciphertext: oldCiphertext,
+			want:       "4111111111111111",
+			wantKeyId:  "2023-01",
+		},
+		{
+			name:       "Legacy CBC",
+			ciphertext: legacyEncrypt("4111111111111111"),
+			want:       "4111111111111111",
+			wantKeyId:  "",
+		},
+		{
+			name:       "Relabelled Key Id",
+			ciphertext: strings.Replace(oldCiphertext, "2023-01", "2024-06", 1),
+			wantKeyId:  "2024-06",
+			wantErr:    errors.New("cipher: message authentication failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := after.Decrypt(tt.ciphertext)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(plaintext) != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", plaintext, tt.want)
+			}
+			if keyId, _ := after.KeyId(tt.ciphertext); keyId != tt.wantKeyId {
+				t.Errorf("KeyId() = %q, want %q", keyId, tt.wantKeyId)
+			}
+		})
+	}
+
+	if newCiphertext == again {
+		t.Errorf("Encrypt() reused a nonce, got the same ciphertext twice")
+	}
+
+	firstIndex, _ := before.BlindIndex("4111111111111111")
+	secondIndex, _ := after.BlindIndex("4111111111111111")
+	if firstIndex != secondIndex {
+		t.Errorf("BlindIndex() changed across key rotation")
+	}
+}
+
+func TestEncryptedString(t *testing.T) {
+	crypto.SetDefault(newEncryptor(t, activeKey))
+
+	value, err := crypto.EncryptedString("4111111111111111").Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+
+	var scanned crypto.EncryptedString
+	if err := scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if scanned.Plaintext() != "4111111111111111" || scanned.String() == "4111111111111111" {
+		t.Errorf("Scan() = %q, String() = %q", scanned.Plaintext(), scanned.String())
+	}
+}
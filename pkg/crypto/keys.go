@@ -0,0 +1,42 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Prefixes naming the encoding of a secret, a secret without one is used as raw bytes
+const (
+	KeyPrefixBase64 = "base64:"
+	KeyPrefixHex    = "hex:"
+)
+
+// ParseKey decodes a secret written as "base64:<standard base64>" or "hex:<hex>", so a 32 byte
+// key can be kept printable in the secret file. A secret without prefix is used as raw bytes,
+// as CARD_SECRET_KEY and CARD_IV always were.
+//
+// Returns an error when a prefixed secret doesn't decode.
+func ParseKey(secret string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(secret, KeyPrefixBase64):
+		key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, KeyPrefixBase64))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 key: %w", err)
+		}
+		return key, nil
+	case strings.HasPrefix(secret, KeyPrefixHex):
+		key, err := hex.DecodeString(strings.TrimPrefix(secret, KeyPrefixHex))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex key: %w", err)
+		}
+		return key, nil
+	default:
+		return []byte(secret), nil
+	}
+}
+
+func isAESKeySize(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
@@ -0,0 +1,38 @@
+package crypto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"boilerplate-service/pkg/crypto"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		want    []byte
+		wantErr bool
+	}{
+		{name: "Base64", secret: "base64:AQIDBA==", want: []byte{1, 2, 3, 4}},
+		{name: "Hex", secret: "hex:01020304", want: []byte{1, 2, 3, 4}},
+		// Raw secrets are never guessed as an encoding, even when they happen to decode
+		{name: "Raw That Looks Like Base64", secret: "AQIDBA==", want: []byte("AQIDBA==")},
+		{name: "Raw That Looks Like Hex", secret: "0102030405060708090a0b0c0d0e0f10", want: []byte("0102030405060708090a0b0c0d0e0f10")},
+		{name: "Invalid Base64", secret: "base64:not base64!", wantErr: true},
+		{name: "Invalid Hex", secret: "hex:zz", wantErr: true},
+		{name: "Empty", secret: "", want: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := crypto.ParseKey(tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, tt.want) {
+				t.Errorf("ParseKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+)
+
+// decryptLegacy opens base64 AES-CBC ciphertexts written with the static SecuritySecret IV.
+// They are read-only: Encrypt always produces AES-GCM and the rotate command rewrites them.
+func (f *fieldEncryptor) decryptLegacy(ciphertext string) ([]byte, error) {
+	if f.legacy == nil {
+		return nil, ErrNoLegacyKey
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(raw) == 0 || len(raw)%aes.BlockSize != 0 {
+		return nil, ErrMalformed
+	}
+
+	plaintext := make([]byte, len(raw))
+	cipher.NewCBCDecrypter(f.legacy, f.legacyIV).CryptBlocks(plaintext, raw)
+
+	return unpadPKCS7(plaintext)
+}
+
+func unpadPKCS7(data []byte) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, ErrMalformed
+	}
+
+	if !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrMalformed
+	}
+
+	return data[:len(data)-padding], nil
+}
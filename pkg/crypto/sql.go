@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrNoDefaultEncryptor = errors.New("crypto.SetDefault was not called")
+
+var (
+	defaultMu        sync.RWMutex
+	defaultEncryptor IFieldEncryptor
+)
+
+// SetDefault sets the encryptor used by EncryptedString when reading and writing columns
+func SetDefault(encryptor IFieldEncryptor) {
+	defaultMu.Lock()
+	defaultEncryptor = encryptor
+	defaultMu.Unlock()
+}
+
+// Default returns the encryptor set with SetDefault
+func Default() (IFieldEncryptor, error) {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	if defaultEncryptor == nil {
+		return nil, ErrNoDefaultEncryptor
+	}
+	return defaultEncryptor, nil
+}
+
+// EncryptedString is a string stored encrypted. Columns of this type round-trip transparently
+// through IMySqlExt: the value is sealed with the active key on write and opened on scan.
+//
+// e.g.
+//
+//	type Card struct {
+//		Number      crypto.EncryptedString `db:"card_number"`
+//		NumberIndex string                 `db:"card_number_bidx"`
+//	}
+type EncryptedString string
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	encryptor, err := Default()
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptor.Encrypt([]byte(s))
+}
+
+func (s *EncryptedString) Scan(src interface{}) error {
+	var ciphertext string
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		ciphertext = v
+	case []byte:
+		ciphertext = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into EncryptedString", src)
+	}
+
+	if ciphertext == "" {
+		*s = ""
+		return nil
+	}
+
+	encryptor, err := Default()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := encryptor.Decrypt(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// String keeps the plaintext out of fmt and logs
+func (s EncryptedString) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[ENCRYPTED]"
+}
+
+// Plaintext returns the decrypted value
+func (s EncryptedString) Plaintext() string {
+	return string(s)
+}
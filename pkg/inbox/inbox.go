@@ -0,0 +1,43 @@
+package inbox
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/rabbitMQExt"
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// IStore remembers which messages a consumer already processed
+type IStore interface {
+	// Guard runs fn unless messageID was already processed by consumer,
+	// in which case it returns duplicate=true without calling fn.
+	Guard(ctx context.Context, consumer, messageID string, fn func(ctx context.Context) error) (duplicate bool, err error)
+}
+
+// Dedupe wraps handler so a redelivered message is acked and skipped instead of applied twice.
+// consumer namespaces message ids, usually the queue name.
+func Dedupe(store IStore, consumer string, logger logger.ILogger, handler rabbitMQExt.Handler) rabbitMQExt.Handler {
+	return func(ctx context.Context, delivery amqp.Delivery) error {
+		if delivery.MessageId == "" {
+			logger.Warn(ctx, "Message without id, deduplication skipped", zap.String("consumer", consumer))
+			return handler(ctx, delivery)
+		}
+
+		duplicate, err := store.Guard(ctx, consumer, delivery.MessageId, func(ctx context.Context) error {
+			return handler(ctx, delivery)
+		})
+		if duplicate {
+			logger.Info(
+				ctx,
+				"Duplicate message skipped",
+				zap.String("consumer", consumer),
+				zap.String("message_id", delivery.MessageId),
+				zap.Bool("redelivered", delivery.Redelivered),
+			)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,55 @@
+package inbox
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/mySqlExt"
+	"context"
+	"fmt"
+)
+
+type mySqlStore struct {
+	db        mySqlExt.IMySqlExt
+	tableName string
+}
+
+const defaultTableName = "inbox"
+
+// NewMySQLStore records processed messages in tableName inside the same transaction
+// as the handler, so the inbox row and the handler's writes commit or roll back together.
+func NewMySQLStore(db mySqlExt.IMySqlExt, tableName string) IStore {
+	if tableName == "" {
+		tableName = defaultTableName
+	}
+
+	return &mySqlStore{db, tableName}
+}
+
+func (s *mySqlStore) Guard(ctx context.Context, consumer, messageID string, fn func(ctx context.Context) error) (bool, error) {
+	duplicate := false
+
+	ctx = context.WithValue(ctx, constant.CtxSQLTableNameKey, s.tableName)
+	err := s.db.WithTx(ctx, nil, func(ctx context.Context) error {
+		// A concurrent delivery of the same message blocks on the primary key until this transaction ends
+		result, err := s.db.ExecContext(ctx, fmt.Sprintf(
+			"INSERT IGNORE INTO `%s` (`consumer`, `message_id`) VALUES (?, ?)",
+			s.tableName,
+		), consumer, messageID)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		return fn(ctx)
+	})
+
+	return duplicate, err
+}
@@ -0,0 +1,100 @@
+package inbox
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/rabbitMQExt"
+	"boilerplate-service/pkg/redisExt"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var ErrInProgress = errors.New("message is being processed by another consumer")
+
+type RedisStoreConfig struct {
+	// TTL is how long processed message ids are remembered, 7 days by default. It must outlast
+	// the longest time a message can be redelivered, e.g. its stay in a retry or dead letter queue.
+	TTL time.Duration
+	// ProcessingTTL is how long a delivery owns a message id before another delivery may run it.
+	// It must be longer than the handler's timeout, or a slow handler can run twice in parallel.
+	ProcessingTTL time.Duration
+
+	Logger logger.ILogger
+}
+
+type redisStore struct {
+	redis  redisExt.IRedisExt
+	config RedisStoreConfig
+}
+
+const (
+	statusProcessing = "processing"
+	statusDone       = "done"
+
+	defaultTTL           = 7 * 24 * time.Hour
+	defaultProcessingTTL = 5 * time.Minute
+)
+
+// NewRedisStore remembers processed message ids for config.TTL. Unlike the MySQL store the
+// marker is not atomic with the handler's side effects, so handlers should still be
+// safe to retry after a crash between the side effect and the marker write.
+//
+// A delivery racing one still in progress is requeued with ErrInProgress, the consumer
+// should set ConsumerConfig.RetryDelay so it waits instead of spinning.
+func NewRedisStore(redis redisExt.IRedisExt, config RedisStoreConfig) IStore {
+	// A 0 TTL would keep the done markers forever
+	if config.TTL == 0 {
+		config.TTL = defaultTTL
+	}
+
+	if config.ProcessingTTL == 0 {
+		config.ProcessingTTL = defaultProcessingTTL
+	}
+
+	return &redisStore{redis, config}
+}
+
+func (s *redisStore) Guard(ctx context.Context, consumer, messageID string, fn func(ctx context.Context) error) (bool, error) {
+	key := fmt.Sprintf("inbox:%s:%s", consumer, messageID)
+
+	claimed, err := s.redis.SetNX(ctx, key, statusProcessing, s.config.ProcessingTTL).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if !claimed {
+		status, err := s.redis.Get(ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		// A marker that expired in between is retried as well
+		if status != statusDone {
+			// Don't ack: the first delivery may still fail and need this one
+			return false, rabbitMQExt.Requeue(ErrInProgress)
+		}
+		return true, nil
+	}
+
+	if err := fn(ctx); err != nil {
+		s.redis.Del(context.WithoutCancel(ctx), key)
+		return false, err
+	}
+
+	// fn already succeeded, failing now would redeliver and apply the message twice.
+	// The processing marker still blocks duplicates until it expires.
+	if err := s.redis.Set(context.WithoutCancel(ctx), key, statusDone, s.config.TTL).Err(); err != nil && s.config.Logger != nil {
+		s.config.Logger.Error(
+			ctx,
+			"Inbox marker write failed after the message was processed",
+			zap.String("consumer", consumer),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,146 @@
+package inbox_test
+
+import (
+	"boilerplate-service/pkg/inbox"
+	"boilerplate-service/pkg/redisExt/redisTest"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedisStoreGuard(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	errRedis := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		config inbox.RedisStoreConfig
+		// marker is the status already stored for the message, empty for none
+		marker        string
+		redisErr      error
+		fnErr         error
+		wantDuplicate bool
+		wantErr       error
+		wantCalled    bool
+		// wantMarker is the status left behind, empty when the key must be gone
+		wantMarker     string
+		wantExpiration time.Duration
+	}{
+		{
+			name:           "First Delivery Is Processed",
+			wantCalled:     true,
+			wantMarker:     "done",
+			wantExpiration: 7 * 24 * time.Hour,
+		},
+		{
+			name:           "Configured TTL",
+			config:         inbox.RedisStoreConfig{TTL: time.Hour},
+			wantCalled:     true,
+			wantMarker:     "done",
+			wantExpiration: time.Hour,
+		},
+		{
+			name:          "Processed Message Is A Duplicate",
+			marker:        "done",
+			wantDuplicate: true,
+			wantMarker:    "done",
+		},
+		{
+			name:       "Message In Progress Is Requeued",
+			marker:     "processing",
+			wantErr:    inbox.ErrInProgress,
+			wantMarker: "processing",
+		},
+		{
+			name:       "Failed Handler Releases The Claim",
+			fnErr:      errHandler,
+			wantErr:    errHandler,
+			wantCalled: true,
+		},
+		{
+			name:     "Redis Error",
+			redisErr: errRedis,
+			wantErr:  errRedis,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := redisTest.New()
+			store := inbox.NewRedisStore(redis, tt.config)
+			key := "inbox:orders:m1"
+
+			if tt.marker != "" {
+				redis.Set(context.Background(), key, tt.marker, time.Minute)
+			}
+			redis.SetErr(tt.redisErr)
+
+			called := false
+			duplicate, err := store.Guard(context.Background(), "orders", "m1", func(ctx context.Context) error {
+				called = true
+				return tt.fnErr
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Guard() error = %v, want %v", err, tt.wantErr)
+			}
+			if duplicate != tt.wantDuplicate {
+				t.Errorf("Guard() duplicate = %v, want %v", duplicate, tt.wantDuplicate)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+
+			redis.SetErr(nil)
+			if marker, _ := redis.Value(key); marker != tt.wantMarker {
+				t.Errorf("marker = %q, want %q", marker, tt.wantMarker)
+			}
+			if tt.wantExpiration != 0 && redis.Expiration(key) != tt.wantExpiration {
+				t.Errorf("expiration = %v, want %v", redis.Expiration(key), tt.wantExpiration)
+			}
+		})
+	}
+}
+
+func TestRedisStoreGuardRetriesAfterFailure(t *testing.T) {
+	store := inbox.NewRedisStore(redisTest.New(), inbox.RedisStoreConfig{})
+
+	calls := 0
+	fn := func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}
+
+	// The redelivery of a failed message runs again, the one after its success is skipped
+	for i, wantDuplicate := range []bool{false, false, true} {
+		duplicate, _ := store.Guard(context.Background(), "orders", "m1", fn)
+		if duplicate != wantDuplicate {
+			t.Errorf("Guard() #%d duplicate = %v, want %v", i, duplicate, wantDuplicate)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestRedisStoreGuardMarkerWriteFailure(t *testing.T) {
+	redis := redisTest.New()
+	store := inbox.NewRedisStore(redis, inbox.RedisStoreConfig{})
+
+	// fn already applied the message, a failing marker write must not redeliver it
+	duplicate, err := store.Guard(context.Background(), "orders", "m1", func(ctx context.Context) error {
+		redis.SetErr(errors.New("connection refused"))
+		return nil
+	})
+	if duplicate || err != nil {
+		t.Errorf("Guard() = %v, %v, want the message acked", duplicate, err)
+	}
+
+	redis.SetErr(nil)
+	if marker, _ := redis.Value("inbox:orders:m1"); marker != "processing" {
+		t.Errorf("marker = %q, want the processing marker kept", marker)
+	}
+}
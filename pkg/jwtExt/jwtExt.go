@@ -0,0 +1,281 @@
+package jwtExt
+
+import (
+	"boilerplate-service/pkg/logger"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMissingKey    = errors.New("jwt has no matching verification key")
+	ErrAudience      = errors.New("jwt audience is not accepted")
+	ErrNoVerifyKey   = errors.New("jwt requires HMACKey or JWKSFile")
+	ErrMissingClaims = errors.New("jwt is missing the subject claim")
+	ErrWeakHMACKey   = errors.New("jwt HMACKey must be at least 32 bytes")
+)
+
+type IJwtExt interface {
+	// Verify checks the token signature and exp/nbf/iss/aud claims.
+	//
+	// Returns the Principal carried by the token.
+	Verify(ctx context.Context, token string) (*Principal, error)
+	Close() error
+}
+
+type Config struct {
+	// HMACKey enables HS256 tokens, it must be at least 32 bytes
+	HMACKey string
+	// JWKSFile enables RS256 and ES256 tokens, the file is reloaded when it changes
+	JWKSFile            string
+	JWKSRefreshInterval time.Duration
+
+	Issuer string
+	// Audience accepts tokens issued for any of these audiences
+	Audience []string
+	// Leeway tolerates clock skew on exp and nbf
+	Leeway time.Duration
+
+	Logger logger.ILogger
+}
+
+type jwtExt struct {
+	config  Config
+	methods []string
+
+	mu      sync.RWMutex
+	keys    *keySet
+	modTime time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+const (
+	defaultJWKSRefreshInterval = time.Minute
+	// minHMACKeySize is the HS256 output size, RFC 7518 forbids shorter keys
+	minHMACKeySize = 32
+)
+
+func New(config Config) (IJwtExt, error) {
+	if config.HMACKey == "" && config.JWKSFile == "" {
+		return nil, ErrNoVerifyKey
+	}
+
+	if config.HMACKey != "" && len(config.HMACKey) < minHMACKeySize {
+		return nil, ErrWeakHMACKey
+	}
+
+	if config.JWKSRefreshInterval == 0 {
+		config.JWKSRefreshInterval = defaultJWKSRefreshInterval
+	}
+
+	j := &jwtExt{config: config, stop: make(chan struct{})}
+
+	if config.HMACKey != "" {
+		j.methods = append(j.methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if config.JWKSFile != "" {
+		if err := j.reloadKeys(); err != nil {
+			return nil, err
+		}
+		j.methods = append(j.methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+
+		go j.watchKeys()
+	}
+
+	return j, nil
+}
+
+func (j *jwtExt) Verify(ctx context.Context, tokenString string) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(j.methods),
+		jwt.WithLeeway(j.config.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if j.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(j.config.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, j.keyFunc, options...); err != nil {
+		return nil, err
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil {
+		return nil, err
+	}
+	if len(j.config.Audience) > 0 && !containsAny(audience, j.config.Audience) {
+		return nil, ErrAudience
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		return nil, ErrMissingClaims
+	}
+
+	issuer, _ := claims.GetIssuer()
+	principal := &Principal{
+		Subject:  subject,
+		Issuer:   issuer,
+		Audience: audience,
+		Scopes:   scopesFromClaims(claims),
+		Roles:    stringsFromClaim(claims["roles"]),
+		Claims:   claims,
+	}
+	if expiresAt, _ := claims.GetExpirationTime(); expiresAt != nil {
+		principal.ExpiresAt = expiresAt.Time
+	}
+
+	return principal, nil
+}
+
+func (j *jwtExt) Close() error {
+	j.once.Do(func() { close(j.stop) })
+	return nil
+}
+
+// keyFunc picks the verification key from the token alg, and kid for asymmetric ones
+func (j *jwtExt) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(j.config.HMACKey), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := j.lookupKey(kid)
+		if !ok {
+			// The issuer may have rotated, pick up the new file without waiting for the next tick
+			if err := j.reloadKeysIfChanged(); err != nil {
+				j.logError("JWKS reload failed", err)
+			}
+			key, ok = j.lookupKey(kid)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: kid %q", ErrMissingKey, kid)
+		}
+
+		return key, nil
+	default:
+		return nil, ErrMissingKey
+	}
+}
+
+func (j *jwtExt) lookupKey(kid string) (crypto.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if j.keys == nil {
+		return nil, false
+	}
+	return j.keys.lookup(kid)
+}
+
+func (j *jwtExt) watchKeys() {
+	ticker := time.NewTicker(j.config.JWKSRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			if err := j.reloadKeysIfChanged(); err != nil {
+				j.logError("JWKS reload failed, keeping previous keys", err)
+			}
+		}
+	}
+}
+
+func (j *jwtExt) reloadKeysIfChanged() error {
+	info, err := os.Stat(j.config.JWKSFile)
+	if err != nil {
+		return err
+	}
+
+	j.mu.RLock()
+	unchanged := info.ModTime().Equal(j.modTime)
+	j.mu.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	return j.reloadKeys()
+}
+
+func (j *jwtExt) reloadKeys() error {
+	info, err := os.Stat(j.config.JWKSFile)
+	if err != nil {
+		return err
+	}
+
+	keys, err := loadKeySet(j.config.JWKSFile)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.modTime = info.ModTime()
+	j.mu.Unlock()
+
+	if j.config.Logger != nil {
+		j.config.Logger.Info(context.Background(), "JWKS loaded", zap.String("file", j.config.JWKSFile), zap.Int("keys", len(keys.keys)))
+	}
+
+	return nil
+}
+
+func (j *jwtExt) logError(msg string, err error) {
+	if j.config.Logger != nil {
+		j.config.Logger.Error(context.Background(), msg, zap.String("file", j.config.JWKSFile), zap.Error(err))
+	}
+}
+
+// scopesFromClaims reads the OAuth2 "scope" claim (space separated) or the "scp" list some issuers use
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return stringsFromClaim(claims["scp"])
+}
+
+func stringsFromClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
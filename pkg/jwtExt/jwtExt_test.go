@@ -0,0 +1,312 @@
+package jwtExt_test
+
+import (
+	"boilerplate-service/pkg/jwtExt"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "boilerplate"
+	testHMACKey  = "0123456789abcdef0123456789abcdef"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+// writeJWKS writes the public keys by kid and bumps the file time so a reload notices the change
+func writeJWKS(t *testing.T, path string, keys map[string]*rsa.PrivateKey) {
+	t.Helper()
+
+	jwks := map[string][]map[string]string{"keys": {}}
+	for kid, key := range keys {
+		jwks["keys"] = append(jwks["keys"], map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	raw, _ := json.Marshal(jwks)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	modTime := time.Now().Add(time.Duration(len(keys)) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "orders:read orders:write",
+		"roles": []string{"admin"},
+	}
+}
+
+func withClaims(changes map[string]interface{}) jwt.MapClaims {
+	claims := validClaims()
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func signHS256(t *testing.T, key []byte, claims jwt.MapClaims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  jwtExt.Config
+		wantErr error
+	}{
+		{name: "HMAC Key", config: jwtExt.Config{HMACKey: testHMACKey}},
+		{name: "Short HMAC Key", config: jwtExt.Config{HMACKey: "secret"}, wantErr: jwtExt.ErrWeakHMACKey},
+		{name: "No Key", config: jwtExt.Config{}, wantErr: jwtExt.ErrNoVerifyKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := jwtExt.New(tt.config)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+			if verifier != nil {
+				verifier.Close()
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, map[string]*rsa.PrivateKey{"key-1": rsaKey})
+
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: func() []byte { raw, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey); return raw }(),
+	})
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  jwtExt.Config
+		token   string
+		wantErr bool
+	}{
+		{
+			name:   "Valid RS256",
+			config: jwtExt.Config{JWKSFile: jwksFile},
+			token:  signRS256(t, rsaKey, "key-1", validClaims()),
+		},
+		{
+			name:   "Valid HS256",
+			config: jwtExt.Config{HMACKey: testHMACKey},
+			token:  signHS256(t, []byte(testHMACKey), validClaims()),
+		},
+		{
+			name:    "HS256 Signed With The RSA Public Key",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signHS256(t, publicKeyPEM, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "HS256 Signed With The RSA Public Key Next To An HMAC Key",
+			config:  jwtExt.Config{JWKSFile: jwksFile, HMACKey: testHMACKey},
+			token:   signHS256(t, publicKeyPEM, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "Alg None",
+			config:  jwtExt.Config{JWKSFile: jwksFile, HMACKey: testHMACKey},
+			token:   unsigned,
+			wantErr: true,
+		},
+		{
+			name:    "Expired",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})),
+			wantErr: true,
+		},
+		{
+			name:   "Expired Within Leeway",
+			config: jwtExt.Config{JWKSFile: jwksFile, Leeway: time.Minute},
+			token:  signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"exp": time.Now().Add(-10 * time.Second).Unix()})),
+		},
+		{
+			name:    "Missing Expiry",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"exp": nil})),
+			wantErr: true,
+		},
+		{
+			name:    "Not Yet Valid",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong Issuer",
+			config:  jwtExt.Config{JWKSFile: jwksFile, Issuer: testIssuer},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"iss": "https://evil.example.com"})),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong Audience",
+			config:  jwtExt.Config{JWKSFile: jwksFile, Audience: []string{testAudience}},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"aud": "another-service"})),
+			wantErr: true,
+		},
+		{
+			name:   "One Of Several Audiences",
+			config: jwtExt.Config{JWKSFile: jwksFile, Audience: []string{"another-service", testAudience}},
+			token:  signRS256(t, rsaKey, "key-1", validClaims()),
+		},
+		{
+			name:    "Missing Subject",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, rsaKey, "key-1", withClaims(map[string]interface{}{"sub": nil})),
+			wantErr: true,
+		},
+		{
+			name:    "Unknown Kid",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, rsaKey, "key-2", validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "Signed By Another Key",
+			config:  jwtExt.Config{JWKSFile: jwksFile},
+			token:   signRS256(t, newRSAKey(t), "key-1", validClaims()),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := jwtExt.New(tt.config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer verifier.Close()
+
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if principal.Subject != "user-1" || !principal.HasScopes("orders:read", "orders:write") || !principal.HasAnyRole("admin") {
+				t.Errorf("Verify() = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestVerifyReloadsJWKSForUnknownKid(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, map[string]*rsa.PrivateKey{"key-1": oldKey})
+
+	verifier, err := jwtExt.New(jwtExt.Config{JWKSFile: jwksFile, JWKSRefreshInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer verifier.Close()
+
+	token := signRS256(t, newKey, "key-2", validClaims())
+	if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, jwtExt.ErrMissingKey) {
+		t.Fatalf("Verify() before rotation error = %v, want ErrMissingKey", err)
+	}
+
+	// The issuer rotates, the new kid is picked up without waiting for the refresh interval
+	writeJWKS(t, jwksFile, map[string]*rsa.PrivateKey{"key-1": oldKey, "key-2": newKey})
+
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify() after rotation error = %v", err)
+	}
+}
+
+func TestPrincipalScopesAndRoles(t *testing.T) {
+	principal := &jwtExt.Principal{Scopes: []string{"orders:read", "orders:write"}, Roles: []string{"support"}}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{name: "Every Scope Granted", got: principal.HasScopes("orders:read", "orders:write"), want: true},
+		{name: "One Scope Missing", got: principal.HasScopes("orders:read", "orders:delete"), want: false},
+		{name: "No Scope Required", got: principal.HasScopes(), want: true},
+		{name: "One Of The Roles", got: principal.HasAnyRole("admin", "support"), want: true},
+		{name: "None Of The Roles", got: principal.HasAnyRole("admin"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package jwtExt
+
+import (
+	"boilerplate-service/constant"
+	"context"
+	"time"
+)
+
+// Principal is the authenticated caller extracted from a verified token
+type Principal struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	Scopes    []string
+	Roles     []string
+	ExpiresAt time.Time
+	// Claims keeps every claim of the token, for service specific ones
+	Claims map[string]interface{}
+}
+
+// HasScopes reports whether the principal was granted every scope
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyRole reports whether the principal holds at least one of roles
+func (p *Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx carrying principal, its subject is also stored
+// under constant.CtxSubjectKey so the logger can pick it up.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	ctx = context.WithValue(ctx, constant.CtxPrincipalKey, principal)
+	return context.WithValue(ctx, constant.CtxSubjectKey, principal.Subject)
+}
+
+// GetPrincipalFromCtx returns the principal set by the auth middleware, nil for anonymous requests
+func GetPrincipalFromCtx(ctx context.Context) *Principal {
+	if principal, ok := ctx.Value(constant.CtxPrincipalKey).(*Principal); ok {
+		return principal
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
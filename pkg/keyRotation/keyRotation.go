@@ -0,0 +1,352 @@
+package keyRotation
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/crypto"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/mySqlExt"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+type IKeyRotation interface {
+	// Rotate re-encrypts every column of table that isn't sealed with the active key yet,
+	// resuming after the last checkpointed primary key. The checkpoint is cleared once the run
+	// completes, so the next run scans the whole table again.
+	Rotate(ctx context.Context, table Table) (Result, error)
+}
+
+// Table is a table holding encrypted columns
+type Table struct {
+	Name string
+	// PrimaryKey must be unique and sortable, it is used for keyset pagination
+	PrimaryKey string
+	Columns    []string
+}
+
+type Result struct {
+	Scanned int
+	Rotated int
+	// LastKey is the primary key the run stopped at
+	LastKey string
+}
+
+type Config struct {
+	// CheckpointTable stores the progress per table and target key id
+	CheckpointTable string
+	BatchSize       int
+	// Throttle is the pause between batches, to keep replication lag and lock time low
+	Throttle time.Duration
+	// Restart ignores the stored checkpoint
+	Restart bool
+	// DryRun counts the values to rotate without writing
+	DryRun bool
+
+	Logger logger.ILogger
+}
+
+type keyRotation struct {
+	db        mySqlExt.IMySqlExt
+	encryptor crypto.IFieldEncryptor
+	config    Config
+}
+
+const (
+	defaultCheckpointTable = "key_rotation_checkpoints"
+	defaultBatchSize       = 500
+)
+
+func New(db mySqlExt.IMySqlExt, encryptor crypto.IFieldEncryptor, config Config) IKeyRotation {
+	if config.CheckpointTable == "" {
+		config.CheckpointTable = defaultCheckpointTable
+	}
+
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBatchSize
+	}
+
+	return &keyRotation{db, encryptor, config}
+}
+
+func (k *keyRotation) Rotate(ctx context.Context, table Table) (Result, error) {
+	result := Result{}
+
+	if err := validateTable(table); err != nil {
+		return result, err
+	}
+
+	targetKeyId := k.encryptor.ActiveKeyId()
+	fields := []zap.Field{zap.String("table", table.Name), zap.String("target_key_id", targetKeyId)}
+
+	if !k.config.Restart {
+		lastKey, err := k.loadCheckpoint(ctx, table, targetKeyId)
+		if err != nil {
+			return result, err
+		}
+		result.LastKey = lastKey
+	}
+	k.config.Logger.Info(ctx, "Key rotation started", append(fields, zap.String("resume_after", result.LastKey))...)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		scanned, rotated, lastKey, err := k.rotateBatch(ctx, table, result.LastKey, targetKeyId)
+		if err != nil {
+			return result, fmt.Errorf("rotate %s after %q: %w", table.Name, result.LastKey, err)
+		}
+
+		result.Scanned += scanned
+		result.Rotated += rotated
+		if scanned > 0 {
+			result.LastKey = lastKey
+		}
+
+		k.config.Logger.Info(
+			ctx,
+			"Key rotation progress",
+			append(fields,
+				zap.Int("scanned", result.Scanned),
+				zap.Int("rotated", result.Rotated),
+				zap.String("last_key", result.LastKey),
+				zap.Bool("dry_run", k.config.DryRun),
+			)...,
+		)
+
+		if scanned < k.config.BatchSize {
+			break
+		}
+
+		if k.config.Throttle > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(k.config.Throttle):
+			}
+		}
+	}
+
+	// Rows behind the checkpoint may have been written with an old key meanwhile,
+	// by pods that didn't have the new active key yet
+	if !k.config.DryRun {
+		if err := k.clearCheckpoint(ctx, table, targetKeyId); err != nil {
+			return result, err
+		}
+	}
+
+	k.config.Logger.Info(ctx, "Key rotation finished", append(fields, zap.Int("scanned", result.Scanned), zap.Int("rotated", result.Rotated))...)
+	return result, nil
+}
+
+// rotateBatch locks the next batch of rows, rewrites values sealed with another key and moves
+// the checkpoint in the same transaction, so a crash never loses or repeats progress.
+func (k *keyRotation) rotateBatch(ctx context.Context, table Table, afterKey, targetKeyId string) (scanned, rotated int, lastKey string, err error) {
+	ctx = context.WithValue(ctx, constant.CtxSQLTableNameKey, table.Name)
+
+	err = k.db.WithTx(ctx, nil, func(ctx context.Context) error {
+		rows, err := k.selectBatch(ctx, table, afterKey)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			scanned++
+			lastKey = row.key
+
+			updates, err := k.reencrypt(row.values, targetKeyId)
+			if err != nil {
+				return fmt.Errorf("%s %s=%s: %w", table.Name, table.PrimaryKey, row.key, err)
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			rotated++
+
+			if k.config.DryRun {
+				continue
+			}
+
+			if err := k.updateRow(ctx, table, row.key, updates); err != nil {
+				return err
+			}
+		}
+
+		if scanned == 0 || k.config.DryRun {
+			return nil
+		}
+
+		return k.saveCheckpoint(ctx, table, targetKeyId, lastKey)
+	})
+
+	return scanned, rotated, lastKey, err
+}
+
+type batchRow struct {
+	key    string
+	values map[string]sql.NullString
+}
+
+func (k *keyRotation) selectBatch(ctx context.Context, table Table, afterKey string) ([]batchRow, error) {
+	columns := make([]string, 0, len(table.Columns)+1)
+	columns = append(columns, quote(table.PrimaryKey))
+	for _, column := range table.Columns {
+		columns = append(columns, quote(column))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), quote(table.Name))
+	args := []interface{}{}
+	if afterKey != "" {
+		query += fmt.Sprintf(" WHERE %s > ?", quote(table.PrimaryKey))
+		args = append(args, afterKey)
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT ?", quote(table.PrimaryKey))
+	args = append(args, k.config.BatchSize)
+
+	if !k.config.DryRun {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := k.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Rows are read fully before updating, the connection can't run both at once
+	batch := []batchRow{}
+	for rows.Next() {
+		var key string
+		values := make([]sql.NullString, len(table.Columns))
+		dest := []interface{}{&key}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := batchRow{key: key, values: map[string]sql.NullString{}}
+		for i, column := range table.Columns {
+			row.values[column] = values[i]
+		}
+		batch = append(batch, row)
+	}
+
+	return batch, rows.Err()
+}
+
+// reencrypt returns the new ciphertext of every column not sealed with targetKeyId
+func (k *keyRotation) reencrypt(values map[string]sql.NullString, targetKeyId string) (map[string]string, error) {
+	updates := map[string]string{}
+	for column, value := range values {
+		if !value.Valid || value.String == "" {
+			continue
+		}
+
+		keyId, err := k.encryptor.KeyId(value.String)
+		if err != nil {
+			return nil, err
+		}
+		if keyId == targetKeyId {
+			continue
+		}
+
+		plaintext, err := k.encryptor.Decrypt(value.String)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %s: %w", column, err)
+		}
+
+		ciphertext, err := k.encryptor.Encrypt(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", column, err)
+		}
+
+		updates[column] = ciphertext
+	}
+
+	return updates, nil
+}
+
+func (k *keyRotation) updateRow(ctx context.Context, table Table, key string, updates map[string]string) error {
+	sets := make([]string, 0, len(updates))
+	args := make([]interface{}, 0, len(updates)+1)
+	for column, ciphertext := range updates {
+		sets = append(sets, quote(column)+" = ?")
+		args = append(args, ciphertext)
+	}
+	args = append(args, key)
+
+	_, err := k.db.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = ?",
+		quote(table.Name), strings.Join(sets, ", "), quote(table.PrimaryKey),
+	), args...)
+
+	return err
+}
+
+func (k *keyRotation) loadCheckpoint(ctx context.Context, table Table, targetKeyId string) (string, error) {
+	ctx = context.WithValue(mySqlExt.WithPrimary(ctx), constant.CtxSQLTableNameKey, k.config.CheckpointTable)
+
+	var lastKey string
+	err := k.db.GetContext(ctx, &lastKey, fmt.Sprintf(
+		"SELECT `last_key` FROM %s WHERE `table_name` = ? AND `target_key_id` = ?",
+		quote(k.config.CheckpointTable),
+	), table.Name, targetKeyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return lastKey, err
+}
+
+func (k *keyRotation) saveCheckpoint(ctx context.Context, table Table, targetKeyId, lastKey string) error {
+	ctx = context.WithValue(ctx, constant.CtxSQLTableNameKey, k.config.CheckpointTable)
+
+	_, err := k.db.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (`table_name`, `target_key_id`, `last_key`) VALUES (?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `last_key` = VALUES(`last_key`), `updated_at` = NOW(6)",
+		quote(k.config.CheckpointTable),
+	), table.Name, targetKeyId, lastKey)
+
+	return err
+}
+
+func (k *keyRotation) clearCheckpoint(ctx context.Context, table Table, targetKeyId string) error {
+	ctx = context.WithValue(ctx, constant.CtxSQLTableNameKey, k.config.CheckpointTable)
+
+	_, err := k.db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE `table_name` = ? AND `target_key_id` = ?",
+		quote(k.config.CheckpointTable),
+	), table.Name, targetKeyId)
+
+	return err
+}
+
+func validateTable(table Table) error {
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("table %q has no encrypted column", table.Name)
+	}
+
+	for _, identifier := range append([]string{table.Name, table.PrimaryKey}, table.Columns...) {
+		if !identifierPattern.MatchString(identifier) {
+			return fmt.Errorf("invalid identifier %q in table %q", identifier, table.Name)
+		}
+	}
+
+	return nil
+}
+
+func quote(identifier string) string {
+	return "`" + identifier + "`"
+}
@@ -0,0 +1,316 @@
+package keyRotation_test
+
+import (
+	"boilerplate-service/pkg/crypto"
+	"boilerplate-service/pkg/keyRotation"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/mySqlExt"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+var (
+	oldKey    = crypto.Key{Id: "2023-01", Secret: bytes.Repeat([]byte{1}, 32)}
+	activeKey = crypto.Key{Id: "2024-06", Secret: bytes.Repeat([]byte{2}, 32)}
+	legacyKey = bytes.Repeat([]byte{3}, 32)
+	legacyIV  = bytes.Repeat([]byte{4}, 16)
+
+	cardsTable = keyRotation.Table{Name: "cards", PrimaryKey: "id", Columns: []string{"number"}}
+)
+
+type nopLogger struct {
+	logger.ILogger
+}
+
+func (nopLogger) Info(ctx context.Context, msg string, fields ...zap.Field) {}
+
+// fakeDB is a single table keyed by primary key plus the checkpoint table, it understands
+// exactly the statements keyRotation sends
+type fakeDB struct {
+	mySqlExt.IMySqlExt
+
+	rows       map[string]string
+	checkpoint string
+	updates    int
+}
+
+func (f *fakeDB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakeDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	afterKey := ""
+	if strings.Contains(query, "WHERE") {
+		afterKey = args[0].(string)
+	}
+	limit := args[len(args)-1].(int)
+
+	keys := make([]string, 0, len(f.rows))
+	for key := range f.rows {
+		if key > afterKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := [][]driver.Value{}
+	for _, key := range keys[:min(limit, len(keys))] {
+		values = append(values, []driver.Value{key, f.rows[key]})
+	}
+
+	return openRows([]string{"id", "number"}, values)
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "UPDATE `cards`"):
+		f.rows[args[1].(string)] = args[0].(string)
+		f.updates++
+	case strings.HasPrefix(query, "INSERT INTO `key_rotation_checkpoints`"):
+		f.checkpoint = args[2].(string)
+	case strings.HasPrefix(query, "DELETE FROM `key_rotation_checkpoints`"):
+		f.checkpoint = ""
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (f *fakeDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if f.checkpoint == "" {
+		return sql.ErrNoRows
+	}
+
+	*dest.(*string) = f.checkpoint
+	return nil
+}
+
+func newEncryptor(t *testing.T, keys ...crypto.Key) crypto.IFieldEncryptor {
+	t.Helper()
+
+	encryptor, err := crypto.New(crypto.Config{Keys: keys, LegacyKey: legacyKey, LegacyIV: legacyIV})
+	if err != nil {
+		t.Fatalf("crypto.New() error = %v", err)
+	}
+	return encryptor
+}
+
+func encrypt(t *testing.T, encryptor crypto.IFieldEncryptor, plaintext string) string {
+	t.Helper()
+
+	ciphertext, err := encryptor.Encrypt([]byte(plaintext))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	return ciphertext
+}
+
+func legacyEncrypt(plaintext string) string {
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, _ := aes.NewCipher(legacyKey)
+	cipher.NewCBCEncrypter(block, legacyIV).CryptBlocks(padded, padded)
+	return base64.StdEncoding.EncodeToString(padded)
+}
+
+func TestRotate(t *testing.T) {
+	before := newEncryptor(t, oldKey)
+	after := newEncryptor(t, oldKey, activeKey)
+
+	tests := []struct {
+		name       string
+		rows       map[string]string
+		checkpoint string
+		config     keyRotation.Config
+		want       keyRotation.Result
+		// wantKeyIds is the key id of every row after the run, "" for rows left as they were
+		wantKeyIds map[string]string
+		wantWrites bool
+	}{
+		{
+			name: "Nothing To Rotate",
+			rows: map[string]string{
+				"1": encrypt(t, after, "4111111111111111"),
+				"2": encrypt(t, after, "4242424242424242"),
+			},
+			want:       keyRotation.Result{Scanned: 2, Rotated: 0, LastKey: "2"},
+			wantKeyIds: map[string]string{"1": activeKey.Id, "2": activeKey.Id},
+		},
+		{
+			name: "Old Key And Legacy To GCM",
+			rows: map[string]string{
+				"1": encrypt(t, before, "4111111111111111"),
+				"2": legacyEncrypt("4242424242424242"),
+				"3": encrypt(t, after, "5555555555554444"),
+			},
+			config:     keyRotation.Config{BatchSize: 2},
+			want:       keyRotation.Result{Scanned: 3, Rotated: 2, LastKey: "3"},
+			wantKeyIds: map[string]string{"1": activeKey.Id, "2": activeKey.Id, "3": activeKey.Id},
+			wantWrites: true,
+		},
+		{
+			name: "Dry Run",
+			rows: map[string]string{
+				"1": encrypt(t, before, "4111111111111111"),
+				"2": legacyEncrypt("4242424242424242"),
+			},
+			config:     keyRotation.Config{DryRun: true},
+			want:       keyRotation.Result{Scanned: 2, Rotated: 2, LastKey: "2"},
+			wantKeyIds: map[string]string{"1": oldKey.Id, "2": ""},
+		},
+		{
+			name: "Resume From Checkpoint",
+			rows: map[string]string{
+				"1": encrypt(t, before, "4111111111111111"),
+				"2": encrypt(t, before, "4242424242424242"),
+				"3": encrypt(t, before, "5555555555554444"),
+			},
+			checkpoint: "1",
+			want:       keyRotation.Result{Scanned: 2, Rotated: 2, LastKey: "3"},
+			wantKeyIds: map[string]string{"1": oldKey.Id, "2": activeKey.Id, "3": activeKey.Id},
+			wantWrites: true,
+		},
+		{
+			name: "Restart Ignores The Checkpoint",
+			rows: map[string]string{
+				"1": encrypt(t, before, "4111111111111111"),
+				"2": encrypt(t, before, "4242424242424242"),
+			},
+			checkpoint: "1",
+			config:     keyRotation.Config{Restart: true},
+			want:       keyRotation.Result{Scanned: 2, Rotated: 2, LastKey: "2"},
+			wantKeyIds: map[string]string{"1": activeKey.Id, "2": activeKey.Id},
+			wantWrites: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintexts := map[string]string{}
+			for key, ciphertext := range tt.rows {
+				plaintext, _ := after.Decrypt(ciphertext)
+				plaintexts[key] = string(plaintext)
+			}
+
+			db := &fakeDB{rows: tt.rows, checkpoint: tt.checkpoint}
+			tt.config.Logger = nopLogger{}
+
+			got, err := keyRotation.New(db, after, tt.config).Rotate(context.Background(), cardsTable)
+			if err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Rotate() = %+v, want %+v", got, tt.want)
+			}
+
+			if (db.updates > 0) != tt.wantWrites {
+				t.Errorf("updates = %d, wantWrites %v", db.updates, tt.wantWrites)
+			}
+
+			// A dry run leaves the checkpoint alone, a completed run clears it
+			wantCheckpoint := ""
+			if tt.config.DryRun {
+				wantCheckpoint = tt.checkpoint
+			}
+			if db.checkpoint != wantCheckpoint {
+				t.Errorf("checkpoint = %q, want %q", db.checkpoint, wantCheckpoint)
+			}
+
+			for key, wantKeyId := range tt.wantKeyIds {
+				keyId, _ := after.KeyId(db.rows[key])
+				if wantKeyId != "" && keyId != wantKeyId {
+					t.Errorf("row %s key id = %q, want %q", key, keyId, wantKeyId)
+				}
+
+				plaintext, err := after.Decrypt(db.rows[key])
+				if err != nil || string(plaintext) != plaintexts[key] {
+					t.Errorf("row %s = %q, %v, want %q", key, plaintext, err, plaintexts[key])
+				}
+			}
+		})
+	}
+}
+
+// fakeRowsDriver hands out canned rows, the fake db has no other way to build a *sql.Rows
+type fakeRowsDriver struct {
+	mu      sync.Mutex
+	results map[string]fakeResult
+}
+
+type fakeResult struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+var (
+	rowsDriver     = &fakeRowsDriver{results: map[string]fakeResult{}}
+	registerDriver sync.Once
+)
+
+func openRows(columns []string, values [][]driver.Value) (*sql.Rows, error) {
+	registerDriver.Do(func() { sql.Register("keyRotationFakeRows", rowsDriver) })
+
+	rowsDriver.mu.Lock()
+	name := string(rune('a' + len(rowsDriver.results)))
+	rowsDriver.results[name] = fakeResult{columns, values}
+	rowsDriver.mu.Unlock()
+
+	db, err := sql.Open("keyRotationFakeRows", name)
+	if err != nil {
+		return nil, err
+	}
+	return db.Query("SELECT")
+}
+
+func (d *fakeRowsDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &fakeConn{result: d.results[name]}, nil
+}
+
+type fakeConn struct {
+	result fakeResult
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.result}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, io.EOF }
+
+type fakeStmt struct {
+	result fakeResult
+}
+
+func (s *fakeStmt) Close() error                                    { return nil }
+func (s *fakeStmt) NumInput() int                                   { return 0 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, io.EOF }
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeDriverRows{result: s.result}, nil
+}
+
+type fakeDriverRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeDriverRows) Columns() []string { return r.result.columns }
+func (r *fakeDriverRows) Close() error      { return nil }
+func (r *fakeDriverRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.values) {
+		return io.EOF
+	}
+	copy(dest, r.result.values[r.next])
+	r.next++
+	return nil
+}
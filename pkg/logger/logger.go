@@ -2,9 +2,13 @@ package logger
 
 import (
 	"boilerplate-service/constant"
+	"boilerplate-service/pkg/traceContext"
+	"boilerplate-service/pkg/util"
 	"context"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type ILogger interface {
@@ -21,6 +25,9 @@ type ILogger interface {
 type Config struct {
 	Environment string
 	ServiceName string
+
+	// Redactor hides sensitive values of every message and field, nil logs them as is
+	Redactor *util.Redactor
 }
 
 type logger struct {
@@ -29,6 +36,7 @@ type logger struct {
 
 func New(config Config) (ILogger, error) {
 	zapConfig := zap.Config{}
+	sampled := false
 
 	if config.Environment == constant.EnvironmentLocal || config.Environment == constant.EnvironmentDevelopment {
 		zapConfig.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
@@ -36,10 +44,7 @@ func New(config Config) (ILogger, error) {
 	} else {
 		zapConfig.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
 		zapConfig.Development = false
-		zapConfig.Sampling = &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		}
+		sampled = true
 	}
 
 	zapConfig.Encoding = "json"
@@ -51,54 +56,66 @@ func New(config Config) (ILogger, error) {
 		"app": config.ServiceName,
 	}
 
-	zapLog, err := zapConfig.Build()
+	// Sampling wraps redaction so sampled-out entries are never redacted
+	zapLog, err := zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if config.Redactor != nil {
+			core = newRedactingCore(core, config.Redactor)
+		}
+		if sampled {
+			core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+		}
+		return core
+	}))
 	return &logger{zapLog}, err
 }
 
-func (l *logger) getTraceId(ctx context.Context) string {
-	if ctx.Value(constant.CtxTraceIdKey) != nil {
-		return ctx.Value(constant.CtxTraceIdKey).(string)
+func (l *logger) getSubject(ctx context.Context) string {
+	if subject, ok := ctx.Value(constant.CtxSubjectKey).(string); ok {
+		return subject
 	}
 	return ""
 }
 
-func (l *logger) Debug(ctx context.Context, msg string, fields ...zap.Field) {
-	trace := l.getTraceId(ctx)
-	if trace != "" {
-		fields = append(fields, zap.String("trace_id", trace))
+// withContextFields appends the trace context and authenticated subject found in ctx
+func (l *logger) withContextFields(ctx context.Context, fields []zap.Field) []zap.Field {
+	if trace, ok := traceContext.FromContext(ctx); ok {
+		fields = append(fields,
+			zap.String("trace_id", trace.TraceId),
+			zap.String("span_id", trace.SpanId),
+			zap.String("request_id", trace.RequestId),
+		)
+	}
+
+	subject := l.getSubject(ctx)
+	if subject != "" {
+		fields = append(fields, zap.String("subject", subject))
 	}
+
+	return fields
+}
+
+func (l *logger) Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	fields = l.withContextFields(ctx, fields)
 	l.zapLog.Debug(msg, fields...)
 }
 
 func (l *logger) Info(ctx context.Context, msg string, fields ...zap.Field) {
-	trace := l.getTraceId(ctx)
-	if trace != "" {
-		fields = append(fields, zap.String("trace_id", trace))
-	}
+	fields = l.withContextFields(ctx, fields)
 	l.zapLog.Info(msg, fields...)
 }
 
 func (l *logger) Error(ctx context.Context, msg string, fields ...zap.Field) {
-	trace := l.getTraceId(ctx)
-	if trace != "" {
-		fields = append(fields, zap.String("trace_id", trace))
-	}
+	fields = l.withContextFields(ctx, fields)
 	l.zapLog.Error(msg, fields...)
 }
 
 func (l *logger) Warn(ctx context.Context, msg string, fields ...zap.Field) {
-	trace := l.getTraceId(ctx)
-	if trace != "" {
-		fields = append(fields, zap.String("trace_id", trace))
-	}
+	fields = l.withContextFields(ctx, fields)
 	l.zapLog.Warn(msg, fields...)
 }
 
 func (l *logger) Panic(ctx context.Context, msg string, fields ...zap.Field) {
-	trace := l.getTraceId(ctx)
-	if trace != "" {
-		fields = append(fields, zap.String("trace_id", trace))
-	}
+	fields = l.withContextFields(ctx, fields)
 	l.zapLog.Panic(msg, fields...)
 }
 
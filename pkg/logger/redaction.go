@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"boilerplate-service/pkg/util"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactingCore redacts the message and fields of every entry before encoding it
+type redactingCore struct {
+	zapcore.Core
+	redactor *util.Redactor
+}
+
+func newRedactingCore(core zapcore.Core, redactor *util.Redactor) zapcore.Core {
+	return &redactingCore{core, redactor}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{c.Core.With(c.redact(fields)), c.redactor}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.String(entry.Message)
+	return c.Core.Write(entry, c.redact(fields))
+}
+
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		if c.redactor.IsField(field.Key) {
+			redacted[i] = zap.String(field.Key, util.Redacted)
+			continue
+		}
+
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = c.redactor.String(field.String)
+		case zapcore.ByteStringType:
+			field = zap.ByteString(field.Key, []byte(c.redactor.String(string(field.Interface.([]byte)))))
+		case zapcore.BinaryType:
+			field = zap.Binary(field.Key, []byte(c.redactor.String(string(field.Interface.([]byte)))))
+		case zapcore.ReflectType:
+			field.Interface = c.redactor.Value(field.Interface)
+		case zapcore.StringerType:
+			field = zap.String(field.Key, c.redactor.String(stringOf(field.Interface.(fmt.Stringer))))
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+			// Marshalers such as zap.Strings and zap.Stringers are encoded to maps and slices first
+			encoder := zapcore.NewMapObjectEncoder()
+			field.AddTo(encoder)
+			field = zap.Reflect(field.Key, c.redactor.Value(encoder.Fields[field.Key]))
+		case zapcore.InlineMarshalerType:
+			encoder := zapcore.NewMapObjectEncoder()
+			field.AddTo(encoder)
+			fields := c.redactor.Value(encoder.Fields)
+			field = zap.Inline(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				for key, value := range fields.(map[string]interface{}) {
+					if err := enc.AddReflected(key, value); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+		case zapcore.ErrorType:
+			// The error is only replaced by its message when the message had something to hide
+			if err, ok := field.Interface.(error); ok && err != nil {
+				if message := c.redactor.String(err.Error()); message != err.Error() {
+					field = zap.String(field.Key, message)
+				}
+			}
+		}
+		redacted[i] = field
+	}
+	return redacted
+}
+
+// stringOf calls String like zap does, a nil pointer receiver shouldn't take the entry down
+func stringOf(stringer fmt.Stringer) (s string) {
+	defer func() {
+		if err := recover(); err != nil {
+			s = fmt.Sprintf("PANIC=%v", err)
+		}
+	}()
+	return stringer.String()
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"boilerplate-service/pkg/util"
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	testCard       = "4111111111111111"
+	testMaskedCard = "411111******1111"
+)
+
+type card struct {
+	Number string
+}
+
+func (c card) String() string { return "card " + c.Number }
+
+func (c card) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("number", c.Number)
+	enc.AddString("cvv", "123")
+	return nil
+}
+
+func newTestLogger(t *testing.T) (*zap.Logger, *bytes.Buffer) {
+	t.Helper()
+
+	redactor, err := util.NewRedactor(util.DefaultRedactionConfig())
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}), zapcore.AddSync(buffer), zap.DebugLevel)
+	return zap.New(newRedactingCore(core, redactor)), buffer
+}
+
+func TestRedactingCore(t *testing.T) {
+	tests := []struct {
+		name  string
+		field zap.Field
+		want  string
+	}{
+		{name: "String", field: zap.String("note", "paid with "+testCard), want: `"note":"paid with ` + testMaskedCard + `"`},
+		{name: "Redacted Field Name", field: zap.Int("pin", 1234), want: `"pin":"[REDACTED]"`},
+		{name: "ByteString", field: zap.ByteString("note", []byte(testCard)), want: `"note":"` + testMaskedCard + `"`},
+		{name: "Binary", field: zap.Binary("note", []byte(testCard)), want: `"note":"NDExMTExKioqKioqMTExMQ=="`},
+		{name: "Stringer", field: zap.Stringer("card", card{testCard}), want: `"card":"card ` + testMaskedCard + `"`},
+		{name: "Nil Stringer", field: zap.Stringer("addr", (*net.TCPAddr)(nil)), want: `"addr":"<nil>"`},
+		{name: "Strings", field: zap.Strings("notes", []string{"ok", testCard}), want: `"notes":["ok","` + testMaskedCard + `"]`},
+		{name: "Stringers", field: zap.Stringers("cards", []card{{testCard}}), want: `"cards":["card ` + testMaskedCard + `"]`},
+		{name: "Object", field: zap.Object("card", card{testCard}), want: `"card":{"cvv":"[REDACTED]","number":"` + testMaskedCard + `"}`},
+		{name: "Inline", field: zap.Inline(card{testCard}), want: `"number":"` + testMaskedCard + `"`},
+		{name: "Reflect", field: zap.Any("card", map[string]string{"card_number": testCard}), want: `"card":{"card_number":"[REDACTED]"}`},
+		{name: "Error", field: zap.Error(errors.New("declined " + testCard)), want: `"error":"declined ` + testMaskedCard + `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, buffer := newTestLogger(t)
+			log.Info("charge", tt.field)
+
+			got := buffer.String()
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("log = %s, want it to contain %s", got, tt.want)
+			}
+			if strings.Contains(got, testCard) || strings.Contains(got, `"cvv":"123"`) {
+				t.Errorf("log = %s, leaks the card", got)
+			}
+		})
+	}
+}
+
+func TestRedactingCoreWith(t *testing.T) {
+	log, buffer := newTestLogger(t)
+	log.With(zap.String("note", testCard)).Info("paid with " + testCard)
+
+	got := buffer.String()
+	if strings.Contains(got, testCard) || strings.Count(got, testMaskedCard) != 2 {
+		t.Errorf("log = %s, want the message and the field redacted", got)
+	}
+}
@@ -0,0 +1,446 @@
+package migrationExt
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/mySqlExt"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration file has been modified")
+	ErrMissingFile      = errors.New("applied migration file not found")
+	ErrLockTimeout      = errors.New("unable to acquire migration lock")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+)
+
+// fileNamePattern matches "<version>_<name>.<up|down>.sql"
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+type IMigrationExt interface {
+	Close() error
+	Up(ctx context.Context) error
+	Down(ctx context.Context, steps int) error
+	Goto(ctx context.Context, version int64) error
+	Status(ctx context.Context) ([]Status, error)
+}
+
+type Config struct {
+	MySQL       mySqlExt.Config
+	Dir         string
+	TableName   string
+	LockTimeout time.Duration
+
+	Logger logger.ILogger
+}
+
+// Status describes a single migration file and whether it has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+type migration struct {
+	Version  int64
+	Name     string
+	UpPath   string
+	DownPath string
+	Checksum string
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+type migrationExt struct {
+	db     *sqlx.DB
+	config Config
+}
+
+const (
+	defaultTableName   = "schema_migrations"
+	defaultLockTimeout = 60 * time.Second
+)
+
+func New(config Config) (IMigrationExt, error) {
+	if config.TableName == "" {
+		config.TableName = defaultTableName
+	}
+
+	if config.LockTimeout == 0 {
+		config.LockTimeout = defaultLockTimeout
+	}
+
+	db, err := sqlx.Connect("mysql", mySqlExt.DataSourceName(config.MySQL, "multiStatements=true"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrationExt{db, config}, nil
+}
+
+// Create writes an empty up/down migration pair into dir, versioned by the current UTC timestamp.
+func Create(dir, name string) (upPath, downPath string, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", "", errors.New("migration name is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	upPath = filepath.Join(dir, fmt.Sprintf("%s_%s.up.sql", version, name))
+	downPath = filepath.Join(dir, fmt.Sprintf("%s_%s.down.sql", version, name))
+
+	for _, path := range []string{upPath, downPath} {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		file.Close()
+	}
+
+	return upPath, downPath, nil
+}
+
+func (m *migrationExt) Close() error {
+	return m.db.Close()
+}
+
+func (m *migrationExt) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		migrations, applied, err := m.load(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *migrationExt) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps: %d", steps)
+	}
+
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		migrations, applied, err := m.load(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			if _, ok := applied[migrations[i].Version]; !ok {
+				continue
+			}
+			if err := m.rollback(ctx, conn, migrations[i]); err != nil {
+				return err
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Goto migrates up or down so that every migration <= version is applied and every migration > version is not.
+// Version 0 rolls back everything.
+func (m *migrationExt) Goto(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		migrations, applied, err := m.load(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 {
+			idx := sort.Search(len(migrations), func(i int) bool { return migrations[i].Version >= version })
+			if idx == len(migrations) || migrations[idx].Version != version {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+			}
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[migrations[i].Version]; ok && migrations[i].Version > version {
+				if err := m.rollback(ctx, conn, migrations[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.apply(ctx, conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *migrationExt) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		migrations, err := m.readDir()
+		if err != nil {
+			return err
+		}
+
+		applied, err := m.readApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			status := Status{Version: mig.Version, Name: mig.Name}
+			if row, ok := applied[mig.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = row.AppliedAt
+				status.Modified = row.Checksum != mig.Checksum
+			}
+			result = append(result, status)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// withLock runs fn on a dedicated connection holding a named MySQL lock,
+// so concurrent pods starting at the same time apply migrations one at a time.
+func (m *migrationExt) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lockName := fmt.Sprintf("%s.%s", m.config.MySQL.DBName, m.config.TableName)
+
+	var acquired sql.NullInt64
+	err = conn.GetContext(ctx, &acquired, "SELECT GET_LOCK(?, ?)", lockName, int(m.config.LockTimeout.Seconds()))
+	if err != nil {
+		return err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS `%s` ("+
+			"`version` BIGINT NOT NULL PRIMARY KEY, "+
+			"`name` VARCHAR(255) NOT NULL, "+
+			"`checksum` CHAR(64) NOT NULL, "+
+			"`applied_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"+
+			")",
+		m.config.TableName,
+	))
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// load reads the migration files and applied versions, refusing to continue
+// when an applied migration was modified or removed.
+func (m *migrationExt) load(ctx context.Context, conn *sqlx.Conn) ([]migration, map[int64]appliedMigration, error) {
+	migrations, err := m.readDir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := m.readApplied(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[int64]migration, len(migrations))
+	for _, mig := range migrations {
+		files[mig.Version] = mig
+	}
+
+	for version, row := range applied {
+		mig, ok := files[version]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %d_%s", ErrMissingFile, version, row.Name)
+		}
+		if mig.Checksum != row.Checksum {
+			return nil, nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, row.Name)
+		}
+	}
+
+	return migrations, applied, nil
+}
+
+func (m *migrationExt) readDir() ([]migration, error) {
+	entries, err := os.ReadDir(m.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, mig.Name, match[2])
+		}
+
+		path := filepath.Join(m.config.Dir, entry.Name())
+		if match[3] == "up" {
+			mig.UpPath = path
+		} else {
+			mig.DownPath = path
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.UpPath == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+
+		content, err := os.ReadFile(mig.UpPath)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		mig.Checksum = hex.EncodeToString(sum[:])
+
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func (m *migrationExt) readApplied(ctx context.Context, conn *sqlx.Conn) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	err := conn.SelectContext(ctx, &rows, fmt.Sprintf(
+		"SELECT `version`, `name`, `checksum`, `applied_at` FROM `%s` ORDER BY `version`",
+		m.config.TableName,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+func (m *migrationExt) apply(ctx context.Context, conn *sqlx.Conn, mig migration) error {
+	if err := m.execFile(ctx, conn, mig.UpPath); err != nil {
+		return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+	}
+
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO `%s` (`version`, `name`, `checksum`) VALUES (?, ?, ?)",
+		m.config.TableName,
+	), mig.Version, mig.Name, mig.Checksum)
+	if err != nil {
+		return err
+	}
+
+	m.log(ctx, fmt.Sprintf("Applied migration %d_%s", mig.Version, mig.Name))
+	return nil
+}
+
+func (m *migrationExt) rollback(ctx context.Context, conn *sqlx.Conn, mig migration) error {
+	if mig.DownPath == "" {
+		return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+	}
+
+	if err := m.execFile(ctx, conn, mig.DownPath); err != nil {
+		return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+	}
+
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM `%s` WHERE `version` = ?",
+		m.config.TableName,
+	), mig.Version)
+	if err != nil {
+		return err
+	}
+
+	m.log(ctx, fmt.Sprintf("Rolled back migration %d_%s", mig.Version, mig.Name))
+	return nil
+}
+
+func (m *migrationExt) execFile(ctx context.Context, conn *sqlx.Conn, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(content)) == "" {
+		return nil
+	}
+
+	_, err = conn.ExecContext(ctx, string(content))
+	return err
+}
+
+func (m *migrationExt) log(ctx context.Context, msg string) {
+	if m.config.Logger != nil {
+		m.config.Logger.Info(ctx, msg)
+	}
+}
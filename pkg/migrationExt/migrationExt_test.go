@@ -0,0 +1,249 @@
+package migrationExt
+
+import (
+	"boilerplate-service/pkg/mySqlExt/sqlTest"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestMigrationExt answers the lock queries and lists applied as the applied migrations
+func newTestMigrationExt(dir string, lock int64, applied ...appliedMigration) (*migrationExt, *sqlTest.DB) {
+	db, recorder := sqlTest.Open(func(query string, args []driver.Value) (sqlTest.Result, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT GET_LOCK"):
+			return sqlTest.Result{Columns: []string{"lock"}, Rows: [][]driver.Value{{lock}}}, nil
+
+		case strings.HasPrefix(query, "SELECT `version`"):
+			result := sqlTest.Result{Columns: []string{"version", "name", "checksum", "applied_at"}}
+			for _, row := range applied {
+				result.Rows = append(result.Rows, []driver.Value{row.Version, row.Name, row.Checksum, row.AppliedAt})
+			}
+			return result, nil
+		}
+		return sqlTest.Result{}, nil
+	})
+
+	return &migrationExt{db, Config{Dir: dir, TableName: defaultTableName}}, recorder
+}
+
+func TestReadDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		want     []int64
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name: "Sorted By Version",
+			files: map[string]string{
+				"20240102000000_add_index.up.sql":     "CREATE INDEX ...",
+				"20240102000000_add_index.down.sql":   "DROP INDEX ...",
+				"20240101000000_create_users.up.sql":  "CREATE TABLE users ...",
+				"README.md":                           "not a migration",
+				"20240103000000_Bad-Name.up.sql":      "ignored",
+				"20240104000000_no_direction.sql":     "ignored",
+				"20240101000000_create_users.down.sq": "ignored",
+			},
+			want:     []int64{20240101000000, 20240102000000},
+			wantName: "create_users",
+		},
+		{
+			name:    "Down Without Up",
+			files:   map[string]string{"1_orphan.down.sql": "DROP TABLE orphan"},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate Version",
+			files: map[string]string{
+				"1_first.up.sql":  "SELECT 1",
+				"1_second.up.sql": "SELECT 2",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &migrationExt{config: Config{Dir: writeFiles(t, tt.files)}}
+
+			migrations, err := m.readDir()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			versions := []int64{}
+			for _, mig := range migrations {
+				versions = append(versions, mig.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("versions = %v, want %v", versions, tt.want)
+			}
+			if migrations[0].Name != tt.wantName || migrations[0].Checksum != checksum("CREATE TABLE users ...") {
+				t.Errorf("first migration = %+v", migrations[0])
+			}
+			if migrations[0].DownPath != "" || migrations[1].DownPath == "" {
+				t.Errorf("down paths = %q, %q", migrations[0].DownPath, migrations[1].DownPath)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	files := map[string]string{
+		"1_create_users.up.sql": "CREATE TABLE users (id INT)",
+		"2_add_email.up.sql":    "ALTER TABLE users ADD email TEXT",
+	}
+
+	tests := []struct {
+		name    string
+		applied []appliedMigration
+		wantErr error
+	}{
+		{
+			name:    "Applied Files Unchanged",
+			applied: []appliedMigration{{Version: 1, Name: "create_users", Checksum: checksum(files["1_create_users.up.sql"])}},
+		},
+		{
+			name:    "Applied File Modified",
+			applied: []appliedMigration{{Version: 1, Name: "create_users", Checksum: checksum("CREATE TABLE users (id BIGINT)")}},
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "Applied File Removed",
+			applied: []appliedMigration{{Version: 3, Name: "dropped", Checksum: checksum("")}},
+			wantErr: ErrMissingFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.applied {
+				tt.applied[i].AppliedAt = time.Now().UTC().Truncate(time.Second)
+			}
+			m, _ := newTestMigrationExt(writeFiles(t, files), 1, tt.applied...)
+
+			err := m.withLock(context.Background(), func(conn *sqlx.Conn) error {
+				migrations, applied, err := m.load(context.Background(), conn)
+				if err == nil && (len(migrations) != 2 || len(applied) != len(tt.applied)) {
+					t.Errorf("load() = %v, %v", migrations, applied)
+				}
+				return err
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("load() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUp(t *testing.T) {
+	files := map[string]string{
+		"1_create_users.up.sql":   "CREATE TABLE users (id INT)",
+		"1_create_users.down.sql": "DROP TABLE users",
+		"2_add_email.up.sql":      "ALTER TABLE users ADD email TEXT",
+		"3_empty.up.sql":          "  \n",
+	}
+
+	m, recorder := newTestMigrationExt(writeFiles(t, files), 1, appliedMigration{
+		Version:   1,
+		Name:      "create_users",
+		Checksum:  checksum(files["1_create_users.up.sql"]),
+		AppliedAt: time.Now().UTC().Truncate(time.Second),
+	})
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	// Only the pending migrations run, in order, each followed by its history row
+	want := []string{
+		"SELECT GET_LOCK(?, ?)",
+		"CREATE TABLE IF NOT EXISTS",
+		"SELECT `version`, `name`, `checksum`, `applied_at` FROM `schema_migrations` ORDER BY `version`",
+		"ALTER TABLE users ADD email TEXT",
+		"INSERT INTO `schema_migrations`",
+		"INSERT INTO `schema_migrations`",
+		"SELECT RELEASE_LOCK(?)",
+	}
+	queries := recorder.Queries()
+	if len(queries) != len(want) {
+		t.Fatalf("queries = %q, want %d statements", queries, len(want))
+	}
+	for i, query := range queries {
+		if !strings.HasPrefix(query, want[i]) {
+			t.Errorf("query %d = %q, want %q", i, query, want[i])
+		}
+	}
+
+	if args := recorder.Statements()[5].Args; args[0] != int64(3) || args[2] != checksum("  \n") {
+		t.Errorf("history row of the empty migration = %v", args)
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		lock       int64
+		wantErr    error
+		wantCalled bool
+	}{
+		{name: "Acquired", lock: 1, wantCalled: true},
+		{name: "Timed Out", lock: 0, wantErr: ErrLockTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, recorder := newTestMigrationExt(t.TempDir(), tt.lock)
+
+			called := false
+			err := m.withLock(context.Background(), func(conn *sqlx.Conn) error {
+				called = true
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("withLock() error = %v, want %v", err, tt.wantErr)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+
+			queries := recorder.Queries()
+			released := queries[len(queries)-1] == "SELECT RELEASE_LOCK(?)"
+			if released != tt.wantCalled {
+				t.Errorf("queries = %q, want the lock released only when it was acquired", queries)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package mySqlExt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is a composable WHERE condition used by Repository.FindWhere.
+// Column names are validated against the repository columns when rendered.
+type Filter struct {
+	op       string
+	column   string
+	value    interface{}
+	children []Filter
+}
+
+func Eq(column string, value interface{}) Filter {
+	return Filter{op: "=", column: column, value: value}
+}
+
+func Ne(column string, value interface{}) Filter {
+	return Filter{op: "<>", column: column, value: value}
+}
+
+func Gt(column string, value interface{}) Filter {
+	return Filter{op: ">", column: column, value: value}
+}
+
+func Gte(column string, value interface{}) Filter {
+	return Filter{op: ">=", column: column, value: value}
+}
+
+func Lt(column string, value interface{}) Filter {
+	return Filter{op: "<", column: column, value: value}
+}
+
+func Lte(column string, value interface{}) Filter {
+	return Filter{op: "<=", column: column, value: value}
+}
+
+func Like(column string, pattern string) Filter {
+	return Filter{op: "LIKE", column: column, value: pattern}
+}
+
+// In matches column against a slice of values, expanded through sqlx.In
+func In(column string, values interface{}) Filter {
+	return Filter{op: "IN", column: column, value: values}
+}
+
+func IsNull(column string) Filter {
+	return Filter{op: "IS NULL", column: column}
+}
+
+func IsNotNull(column string) Filter {
+	return Filter{op: "IS NOT NULL", column: column}
+}
+
+func And(filters ...Filter) Filter {
+	return Filter{op: "AND", children: filters}
+}
+
+func Or(filters ...Filter) Filter {
+	return Filter{op: "OR", children: filters}
+}
+
+// isEmpty reports whether the filter has no condition, e.g. the zero Filter or And()
+func (f Filter) isEmpty() bool {
+	return f.op == "" || ((f.op == "AND" || f.op == "OR") && len(f.children) == 0)
+}
+
+// render builds the SQL fragment and its arguments, rejecting unknown columns
+func (f Filter) render(columns map[string]bool) (string, []interface{}, error) {
+	switch f.op {
+	case "AND", "OR":
+		parts := make([]string, 0, len(f.children))
+		var args []interface{}
+		for _, child := range f.children {
+			if child.isEmpty() {
+				continue
+			}
+			part, childArgs, err := child.render(columns)
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, part)
+			args = append(args, childArgs...)
+		}
+		if len(parts) == 0 {
+			return "1 = 1", nil, nil
+		}
+		return "(" + strings.Join(parts, " "+f.op+" ") + ")", args, nil
+	}
+
+	if !columns[f.column] {
+		return "", nil, fmt.Errorf("unknown column %q in filter", f.column)
+	}
+
+	switch f.op {
+	case "IS NULL", "IS NOT NULL":
+		return fmt.Sprintf("`%s` %s", f.column, f.op), nil, nil
+	case "IN":
+		return fmt.Sprintf("`%s` IN (?)", f.column), []interface{}{f.value}, nil
+	default:
+		return fmt.Sprintf("`%s` %s ?", f.column, f.op), []interface{}{f.value}, nil
+	}
+}
@@ -2,6 +2,7 @@ package mySqlExt
 
 import (
 	"boilerplate-service/constant"
+	"boilerplate-service/pkg/logger"
 	"boilerplate-service/pkg/newRelicExt"
 	"context"
 
@@ -22,22 +23,38 @@ type IMySqlExt interface {
 		query string,
 		args ...interface{},
 	) (*sql.Rows, error)
+	NamedQueryContext(
+		ctx context.Context,
+		query string,
+		args interface{},
+	) (*sqlx.Rows, error)
 	ExecContext(
 		ctx context.Context,
 		query string,
 		args ...interface{},
-	) (bool, error)
+	) (sql.Result, error)
 	NamedExecContext(
 		ctx context.Context,
 		query string,
 		args interface{},
-	) (bool, error)
+	) (sql.Result, error)
 	GetContext(
 		ctx context.Context,
 		dest interface{},
 		query string,
 		args ...interface{},
 	) error
+	SelectContext(
+		ctx context.Context,
+		dest interface{},
+		query string,
+		args ...interface{},
+	) error
+	WithTx(
+		ctx context.Context,
+		opts *sql.TxOptions,
+		fn func(ctx context.Context) error,
+	) error
 	Ping() error
 }
 
@@ -51,34 +68,68 @@ type Config struct {
 	MaxIdleTime  int
 	MaxLifeTime  int
 	MaxOpenConns int
+
+	// Replicas receive read traffic, credentials and pool settings are shared with the primary
+	Replicas []Replica
+	// ReplicaHealthCheckInterval is the ping interval in seconds for replicas
+	ReplicaHealthCheckInterval int
+
+	Logger logger.ILogger
+}
+
+type Replica struct {
+	Host string
+	Port string
 }
 
 type mySqlExt struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	replicas *replicaSet
 }
 
 func New(config Config) (IMySqlExt, error) {
-	db, err := sqlx.Connect(
-		"mysql",
-		fmt.Sprintf(
-			"%s:%s@tcp(%s:%s)/%s?parseTime=true",
-			config.Username,
-			config.Password,
-			config.Host,
-			config.Port,
-			config.DBName,
-		),
-	)
+	db, err := sqlx.Connect("mysql", DataSourceName(config))
 	if err != nil {
 		return nil, err
 	}
 
 	setDBConfig(db, config)
 
-	return &mySqlExt{db}, nil
+	replicas, err := newReplicaSet(config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &mySqlExt{db, replicas}, nil
+}
+
+// NewWithDB wraps an open database without replicas, e.g. one from sqlTest.Open
+func NewWithDB(db *sqlx.DB) IMySqlExt {
+	return &mySqlExt{db, &replicaSet{}}
+}
+
+// DataSourceName builds the go-sql-driver DSN for the given config.
+// Extra params (e.g. "multiStatements=true") are appended to the query string.
+func DataSourceName(config Config, params ...string) string {
+	dsn := fmt.Sprintf(
+		"%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		config.Username,
+		config.Password,
+		config.Host,
+		config.Port,
+		config.DBName,
+	)
+
+	for _, param := range params {
+		dsn += "&" + param
+	}
+
+	return dsn
 }
 
 func (m *mySqlExt) Close() error {
+	m.replicas.Close()
 	return m.db.Close()
 }
 
@@ -112,100 +163,128 @@ func (m *mySqlExt) getTableName(ctx context.Context) string {
 	return ""
 }
 
-func (m *mySqlExt) QueryContext(
-	ctx context.Context,
-	query string,
-	args ...interface{},
-) (*sql.Rows, error) {
+// startSegment starts a New Relic datastore segment for query and returns
+// the context carrying the transaction for the driver.
+func (m *mySqlExt) startSegment(ctx context.Context, query string) (context.Context, *newrelic.DatastoreSegment) {
 	txn := newRelicExt.GetTxnFromCtx(ctx)
-	operationsQuery := strings.Split(query, " ")
-	dbSegment := newrelic.DatastoreSegment{
+
+	operation := ""
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	dbSegment := &newrelic.DatastoreSegment{
 		StartTime:  txn.StartSegmentNow(),
 		Product:    newrelic.DatastoreMySQL,
 		Collection: m.getTableName(ctx),
-		Operation:  operationsQuery[0],
+		Operation:  operation,
 		RawQuery:   query,
 	}
-	defer dbSegment.End()
 
-	ctx = newrelic.NewContext(ctx, txn)
+	return newrelic.NewContext(ctx, txn), dbSegment
+}
+
+// expandIn expands slice arguments for "IN (?)" clauses via sqlx.In.
+// Queries without slice arguments are returned unchanged.
+func (m *mySqlExt) expandIn(query string, args []interface{}) (string, []interface{}, error) {
+	if len(args) == 0 {
+		return query, args, nil
+	}
 
-	return m.db.QueryContext(ctx, query, args...)
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return m.db.Rebind(query), args, nil
 }
 
-func (m *mySqlExt) ExecContext(
+func (m *mySqlExt) QueryContext(
 	ctx context.Context,
 	query string,
 	args ...interface{},
-) (bool, error) {
-	txn := newRelicExt.GetTxnFromCtx(ctx)
-	operationsQuery := strings.Split(query, " ")
-	dbSegment := newrelic.DatastoreSegment{
-		StartTime:  txn.StartSegmentNow(),
-		Product:    newrelic.DatastoreMySQL,
-		Collection: m.getTableName(ctx),
-		Operation:  operationsQuery[0],
-		RawQuery:   query,
+) (*sql.Rows, error) {
+	query, args, err := m.expandIn(query, args)
+	if err != nil {
+		return nil, err
 	}
+
+	ctx, dbSegment := m.startSegment(ctx, query)
 	defer dbSegment.End()
 
-	ctx = newrelic.NewContext(ctx, txn)
+	return m.readExecutor(ctx).QueryContext(ctx, query, args...)
+}
 
-	sqlResults, err := m.db.ExecContext(ctx, query, args...)
+func (m *mySqlExt) NamedQueryContext(
+	ctx context.Context,
+	query string,
+	args interface{},
+) (*sqlx.Rows, error) {
+	ctx, dbSegment := m.startSegment(ctx, query)
+	defer dbSegment.End()
+
+	return sqlx.NamedQueryContext(ctx, m.executor(ctx), query, args)
+}
+
+func (m *mySqlExt) ExecContext(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (sql.Result, error) {
+	query, args, err := m.expandIn(query, args)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	affected, err := sqlResults.RowsAffected()
-	return affected != 0, err
+	ctx, dbSegment := m.startSegment(ctx, query)
+	defer dbSegment.End()
+
+	return m.executor(ctx).ExecContext(ctx, query, args...)
 }
 
 func (m *mySqlExt) NamedExecContext(
 	ctx context.Context,
 	query string,
 	args interface{},
-) (bool, error) {
-	txn := newRelicExt.GetTxnFromCtx(ctx)
-	operationsQuery := strings.Split(query, " ")
-	dbSegment := newrelic.DatastoreSegment{
-		StartTime:  txn.StartSegmentNow(),
-		Product:    newrelic.DatastoreMySQL,
-		Collection: m.getTableName(ctx),
-		Operation:  operationsQuery[0],
-		RawQuery:   query,
-	}
+) (sql.Result, error) {
+	ctx, dbSegment := m.startSegment(ctx, query)
 	defer dbSegment.End()
 
-	ctx = newrelic.NewContext(ctx, txn)
+	return m.executor(ctx).NamedExecContext(ctx, query, args)
+}
 
-	sqlResults, err := m.db.NamedExecContext(ctx, query, args)
+func (m *mySqlExt) GetContext(
+	ctx context.Context,
+	dest interface{},
+	query string,
+	args ...interface{},
+) error {
+	query, args, err := m.expandIn(query, args)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	affected, err := sqlResults.RowsAffected()
-	return affected != 0, err
+	ctx, dbSegment := m.startSegment(ctx, query)
+	defer dbSegment.End()
+
+	return m.readExecutor(ctx).GetContext(ctx, dest, query, args...)
 }
 
-func (m *mySqlExt) GetContext(
+func (m *mySqlExt) SelectContext(
 	ctx context.Context,
 	dest interface{},
 	query string,
 	args ...interface{},
 ) error {
-	txn := newRelicExt.GetTxnFromCtx(ctx)
-	dbSegment := newrelic.DatastoreSegment{
-		StartTime:  txn.StartSegmentNow(),
-		Product:    newrelic.DatastoreMySQL,
-		Collection: m.getTableName(ctx),
-		Operation:  "SELECT",
-		RawQuery:   query,
+	query, args, err := m.expandIn(query, args)
+	if err != nil {
+		return err
 	}
-	defer dbSegment.End()
 
-	ctx = newrelic.NewContext(ctx, txn)
+	ctx, dbSegment := m.startSegment(ctx, query)
+	defer dbSegment.End()
 
-	return m.db.GetContext(ctx, dest, query, args...)
+	return m.readExecutor(ctx).SelectContext(ctx, dest, query, args...)
 }
 
 func (m *mySqlExt) Ping() error {
@@ -0,0 +1,160 @@
+package mySqlExt
+
+import (
+	"boilerplate-service/constant"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultReplicaHealthCheckInterval = 5 // seconds
+	replicaPingTimeout                = 2 * time.Second
+)
+
+type replica struct {
+	name    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+}
+
+// replicaSet round-robins reads across healthy replicas and pings them in the background
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	config   Config
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// WithPrimary returns a context whose reads are routed to the primary,
+// e.g. to read a row right after writing it.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, constant.CtxSQLForcePrimaryKey, true)
+}
+
+func newReplicaSet(config Config) (*replicaSet, error) {
+	if config.ReplicaHealthCheckInterval == 0 {
+		config.ReplicaHealthCheckInterval = defaultReplicaHealthCheckInterval
+	}
+
+	set := &replicaSet{config: config, stop: make(chan struct{})}
+	if len(config.Replicas) == 0 {
+		return set, nil
+	}
+
+	for _, r := range config.Replicas {
+		replicaConfig := config
+		replicaConfig.Host = r.Host
+		replicaConfig.Port = r.Port
+
+		// Open does not dial, so an unavailable replica doesn't block startup
+		db, err := sqlx.Open("mysql", DataSourceName(replicaConfig))
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		setDBConfig(db, replicaConfig)
+
+		set.replicas = append(set.replicas, &replica{
+			name: fmt.Sprintf("%s:%s", r.Host, r.Port),
+			db:   db,
+		})
+	}
+
+	// Replicas start unhealthy, reads go to the primary until the first check in healthLoop
+	set.wg.Add(1)
+	go set.healthLoop()
+
+	return set, nil
+}
+
+// pick returns the next healthy replica, or nil when none is available
+func (s *replicaSet) pick() *sqlx.DB {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+
+	start := s.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := s.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return nil
+}
+
+func (s *replicaSet) healthLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.ReplicaHealthCheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	s.checkHealth()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkHealth()
+		}
+	}
+}
+
+func (s *replicaSet) checkHealth() {
+	for _, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy || s.config.Logger == nil {
+			continue
+		}
+
+		if healthy {
+			s.config.Logger.Info(context.Background(), "MySQL replica back in rotation", zap.String("replica", r.name))
+		} else {
+			s.config.Logger.Warn(context.Background(), "MySQL replica removed from rotation", zap.String("replica", r.name), zap.Error(err))
+		}
+	}
+}
+
+func (s *replicaSet) Close() {
+	if len(s.replicas) > 0 {
+		close(s.stop)
+		s.wg.Wait()
+	}
+
+	for _, r := range s.replicas {
+		r.db.Close()
+	}
+}
+
+// readExecutor routes reads to a replica unless ctx is inside a transaction
+// or forced to the primary, falling back to the primary when no replica is healthy.
+func (m *mySqlExt) readExecutor(ctx context.Context) executor {
+	if state := getTxFromCtx(ctx); state != nil {
+		return state.tx
+	}
+
+	if forced, _ := ctx.Value(constant.CtxSQLForcePrimaryKey).(bool); forced {
+		return m.db
+	}
+
+	if db := m.replicas.pick(); db != nil {
+		return db
+	}
+
+	return m.db
+}
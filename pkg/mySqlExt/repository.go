@@ -0,0 +1,389 @@
+package mySqlExt
+
+import (
+	"boilerplate-service/constant"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrStaleVersion is returned by Update when the row version no longer matches (optimistic locking)
+	ErrStaleVersion = errors.New("row was modified by another writer")
+	// ErrNoSoftDelete is returned by SoftDelete when the entity has no softdelete column
+	ErrNoSoftDelete = errors.New("entity has no softdelete column")
+)
+
+// Tabler lets an entity override the table name derived from its type name
+type Tabler interface {
+	TableName() string
+}
+
+// Repository is a typed CRUD helper for an entity T whose fields carry `db` tags.
+//
+// Tag options after the column name mark special columns:
+//
+//	ID        int64        `db:"id,pk"`              // primary key, "id" is used when no field is marked
+//	Version   int64        `db:"version,version"`    // optimistic locking counter
+//	DeletedAt sql.NullTime `db:"deleted_at,softdelete"`
+//	CreatedAt time.Time    `db:"created_at,readonly"` // never written, e.g. DEFAULT CURRENT_TIMESTAMP
+type Repository[T any] struct {
+	db IMySqlExt
+
+	table         string
+	columns       []column
+	columnSet     map[string]bool
+	pk            *column
+	version       *column
+	softDelete    *column
+	selectColumns string
+}
+
+type column struct {
+	name     string
+	index    []int
+	readonly bool
+}
+
+// PageRequest asks for Limit rows after the After cursor (the primary key of the last row seen)
+type PageRequest struct {
+	After interface{}
+	Limit int
+	Desc  bool
+}
+
+type Page[T any] struct {
+	Items      []T
+	NextCursor interface{}
+	HasMore    bool
+}
+
+const defaultPageLimit = 20
+
+func NewRepository[T any](db IMySqlExt) (*Repository[T], error) {
+	var entity T
+	typ := reflect.TypeOf(entity)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("repository entity must be a struct, got %T", entity)
+	}
+
+	r := &Repository[T]{
+		db:        db,
+		table:     toSnakeCase(typ.Name()),
+		columnSet: map[string]bool{},
+	}
+	if tabler, ok := any(&entity).(Tabler); ok {
+		r.table = tabler.TableName()
+	}
+
+	if err := r.parseFields(typ, nil); err != nil {
+		return nil, err
+	}
+
+	if r.pk == nil {
+		for i := range r.columns {
+			if r.columns[i].name == "id" {
+				r.pk = &r.columns[i]
+			}
+		}
+	}
+	if r.pk == nil {
+		return nil, fmt.Errorf("entity %s has no primary key column", typ.Name())
+	}
+
+	names := make([]string, len(r.columns))
+	for i, col := range r.columns {
+		names[i] = quote(col.name)
+	}
+	r.selectColumns = strings.Join(names, ", ")
+
+	return r, nil
+}
+
+func (r *Repository[T]) parseFields(typ reflect.Type, parent []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag, ok := field.Tag.Lookup("db")
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := r.parseFields(field.Type, index); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		col := column{name: parts[0], index: index}
+		if r.columnSet[col.name] {
+			return fmt.Errorf("duplicate column %q", col.name)
+		}
+
+		var role string
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "readonly":
+				col.readonly = true
+			case "pk", "version", "softdelete":
+				role = opt
+			}
+		}
+
+		r.columns = append(r.columns, col)
+		r.columnSet[col.name] = true
+
+		switch role {
+		case "pk":
+			r.pk = &column{name: col.name, index: col.index}
+		case "version":
+			r.version = &column{name: col.name, index: col.index}
+		case "softdelete":
+			r.softDelete = &column{name: col.name, index: col.index}
+		}
+	}
+
+	return nil
+}
+
+// TableName returns the table the repository reads and writes
+func (r *Repository[T]) TableName() string {
+	return r.table
+}
+
+// withTable labels New Relic datastore segments with the repository table
+func (r *Repository[T]) withTable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, constant.CtxSQLTableNameKey, r.table)
+}
+
+// notDeleted returns the soft-delete condition, or "" when the entity has none
+func (r *Repository[T]) notDeleted() string {
+	if r.softDelete == nil {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s IS NULL", quote(r.softDelete.name))
+}
+
+// FindByID returns sql.ErrNoRows when there is no live row with the given id
+func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (T, error) {
+	var entity T
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ?%s",
+		r.selectColumns, quote(r.table), quote(r.pk.name), r.notDeleted(),
+	)
+
+	err := r.db.GetContext(r.withTable(ctx), &entity, query, id)
+	return entity, err
+}
+
+// FindWhere returns every live row matching filter, ordered by primary key
+func (r *Repository[T]) FindWhere(ctx context.Context, filter Filter) ([]T, error) {
+	where, args, err := r.where(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY %s",
+		r.selectColumns, quote(r.table), where, quote(r.pk.name),
+	)
+
+	var entities []T
+	err = r.db.SelectContext(r.withTable(ctx), &entities, query, args...)
+	return entities, err
+}
+
+// FindPage returns live rows matching filter using keyset pagination on the primary key
+func (r *Repository[T]) FindPage(ctx context.Context, filter Filter, page PageRequest) (Page[T], error) {
+	if page.Limit <= 0 {
+		page.Limit = defaultPageLimit
+	}
+
+	cmp, order := ">", "ASC"
+	if page.Desc {
+		cmp, order = "<", "DESC"
+	}
+
+	if page.After != nil {
+		filter = And(filter, Filter{op: cmp, column: r.pk.name, value: page.After})
+	}
+
+	where, args, err := r.where(filter)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY %s %s LIMIT %d",
+		r.selectColumns, quote(r.table), where, quote(r.pk.name), order, page.Limit+1,
+	)
+
+	var entities []T
+	if err := r.db.SelectContext(r.withTable(ctx), &entities, query, args...); err != nil {
+		return Page[T]{}, err
+	}
+
+	result := Page[T]{Items: entities}
+	if len(entities) > page.Limit {
+		result.Items = entities[:page.Limit]
+		result.HasMore = true
+	}
+	if len(result.Items) > 0 {
+		last := reflect.ValueOf(&result.Items[len(result.Items)-1]).Elem()
+		result.NextCursor = last.FieldByIndex(r.pk.index).Interface()
+	}
+
+	return result, nil
+}
+
+func (r *Repository[T]) where(filter Filter) (string, []interface{}, error) {
+	where, args := "1 = 1", []interface{}(nil)
+	if !filter.isEmpty() {
+		var err error
+		where, args, err = filter.render(r.columnSet)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return where + r.notDeleted(), args, nil
+}
+
+// Insert writes entity. A zero integer primary key is left to AUTO_INCREMENT and
+// filled from LastInsertId, and a zero version column starts at 1.
+func (r *Repository[T]) Insert(ctx context.Context, entity *T) (sql.Result, error) {
+	value := reflect.ValueOf(entity).Elem()
+
+	if r.version != nil {
+		if version := value.FieldByIndex(r.version.index); version.IsZero() && version.CanInt() {
+			version.SetInt(1)
+		}
+	}
+
+	pkValue := value.FieldByIndex(r.pk.index)
+	autoIncrement := pkValue.IsZero() && pkValue.CanInt()
+
+	names := make([]string, 0, len(r.columns))
+	placeholders := make([]string, 0, len(r.columns))
+	args := make([]interface{}, 0, len(r.columns))
+	for _, col := range r.columns {
+		if col.readonly || (autoIncrement && col.name == r.pk.name) {
+			continue
+		}
+		names = append(names, quote(col.name))
+		placeholders = append(placeholders, "?")
+		args = append(args, value.FieldByIndex(col.index).Interface())
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quote(r.table), strings.Join(names, ", "), strings.Join(placeholders, ", "),
+	)
+
+	result, err := r.db.ExecContext(r.withTable(ctx), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if autoIncrement {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return result, err
+		}
+		pkValue.SetInt(id)
+	}
+
+	return result, nil
+}
+
+// Update writes every non-readonly column of entity by primary key. When the entity has a
+// version column the update only applies to the loaded version, returns ErrStaleVersion
+// otherwise, and increments the version on entity.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) (sql.Result, error) {
+	value := reflect.ValueOf(entity).Elem()
+
+	sets := make([]string, 0, len(r.columns))
+	args := make([]interface{}, 0, len(r.columns)+2)
+	for _, col := range r.columns {
+		if col.readonly || col.name == r.pk.name || (r.version != nil && col.name == r.version.name) {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = ?", quote(col.name)))
+		args = append(args, value.FieldByIndex(col.index).Interface())
+	}
+
+	where := fmt.Sprintf("%s = ?", quote(r.pk.name))
+	args = append(args, value.FieldByIndex(r.pk.index).Interface())
+
+	var version reflect.Value
+	if r.version != nil {
+		version = value.FieldByIndex(r.version.index)
+		sets = append(sets, fmt.Sprintf("%s = %s + 1", quote(r.version.name), quote(r.version.name)))
+		where += fmt.Sprintf(" AND %s = ?", quote(r.version.name))
+		args = append(args, version.Interface())
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s%s",
+		quote(r.table), strings.Join(sets, ", "), where, r.notDeleted(),
+	)
+
+	result, err := r.db.ExecContext(r.withTable(ctx), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.version != nil {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if affected == 0 {
+			return result, ErrStaleVersion
+		}
+		if version.CanInt() {
+			version.SetInt(version.Int() + 1)
+		}
+	}
+
+	return result, nil
+}
+
+// SoftDelete stamps the softdelete column of a live row with the current time
+func (r *Repository[T]) SoftDelete(ctx context.Context, id interface{}) (sql.Result, error) {
+	if r.softDelete == nil {
+		return nil, ErrNoSoftDelete
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s = NOW() WHERE %s = ?%s",
+		quote(r.table), quote(r.softDelete.name), quote(r.pk.name), r.notDeleted(),
+	)
+
+	return r.db.ExecContext(r.withTable(ctx), query, id)
+}
+
+func quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,351 @@
+package mySqlExt_test
+
+import (
+	"boilerplate-service/pkg/mySqlExt"
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type order struct {
+	ID        int64        `db:"id,pk"`
+	UserID    int64        `db:"user_id"`
+	Status    string       `db:"status"`
+	Version   int64        `db:"version,version"`
+	DeletedAt sql.NullTime `db:"deleted_at,softdelete"`
+	CreatedAt time.Time    `db:"created_at,readonly"`
+	Ignored   string       `db:"-"`
+}
+
+type auditEntry struct {
+	EntryID string `db:"entry_id,pk"`
+	Message string `db:"message"`
+}
+
+func (auditEntry) TableName() string {
+	return "audit_log"
+}
+
+type fakeResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertId, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// fakeDB records the last statement and answers with the configured rows and result
+type fakeDB struct {
+	query string
+	args  []interface{}
+
+	rows   interface{}
+	result fakeResult
+}
+
+func (f *fakeDB) record(query string, args []interface{}) {
+	f.query, f.args = query, args
+}
+
+func (f *fakeDB) Close() error { return nil }
+func (f *fakeDB) Ping() error  { return nil }
+
+func (f *fakeDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	f.record(query, args)
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	f.record(query, nil)
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	f.record(query, args)
+	return f.result, nil
+}
+
+func (f *fakeDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	f.record(query, nil)
+	return f.result, nil
+}
+
+func (f *fakeDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	f.record(query, args)
+	return nil
+}
+
+func (f *fakeDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	f.record(query, args)
+	if f.rows != nil {
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(f.rows))
+	}
+	return nil
+}
+
+func (f *fakeDB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func newOrderRepository(t *testing.T, db *fakeDB) *mySqlExt.Repository[order] {
+	repository, err := mySqlExt.NewRepository[order](db)
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	return repository
+}
+
+func assertStatement(t *testing.T, db *fakeDB, wantQuery string, wantArgs []interface{}) {
+	t.Helper()
+
+	if db.query != wantQuery {
+		t.Errorf("query = %q\nwant    %q", db.query, wantQuery)
+	}
+	if !reflect.DeepEqual(db.args, wantArgs) {
+		t.Errorf("args = %#v, want %#v", db.args, wantArgs)
+	}
+}
+
+func TestNewRepository(t *testing.T) {
+	tests := []struct {
+		name      string
+		newFn     func(db mySqlExt.IMySqlExt) (string, error)
+		wantTable string
+		wantErr   bool
+	}{
+		{
+			name: "Table From Type Name",
+			newFn: func(db mySqlExt.IMySqlExt) (string, error) {
+				r, err := mySqlExt.NewRepository[order](db)
+				if err != nil {
+					return "", err
+				}
+				return r.TableName(), nil
+			},
+			wantTable: "order",
+		},
+		{
+			name: "Table From Tabler",
+			newFn: func(db mySqlExt.IMySqlExt) (string, error) {
+				r, err := mySqlExt.NewRepository[auditEntry](db)
+				if err != nil {
+					return "", err
+				}
+				return r.TableName(), nil
+			},
+			wantTable: "audit_log",
+		},
+		{
+			name: "Missing Primary Key",
+			newFn: func(db mySqlExt.IMySqlExt) (string, error) {
+				_, err := mySqlExt.NewRepository[struct {
+					Name string `db:"name"`
+				}](db)
+				return "", err
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate Column",
+			newFn: func(db mySqlExt.IMySqlExt) (string, error) {
+				_, err := mySqlExt.NewRepository[struct {
+					ID   int64  `db:"id"`
+					Name string `db:"id"`
+				}](db)
+				return "", err
+			},
+			wantErr: true,
+		},
+		{
+			name: "Not A Struct",
+			newFn: func(db mySqlExt.IMySqlExt) (string, error) {
+				_, err := mySqlExt.NewRepository[int](db)
+				return "", err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := tt.newFn(&fakeDB{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if table != tt.wantTable {
+				t.Errorf("TableName() = %q, want %q", table, tt.wantTable)
+			}
+		})
+	}
+}
+
+func TestRepositoryQueries(t *testing.T) {
+	const columns = "`id`, `user_id`, `status`, `version`, `deleted_at`, `created_at`"
+
+	tests := []struct {
+		name      string
+		run       func(r *mySqlExt.Repository[order]) error
+		wantQuery string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{
+			name: "FindByID Skips Soft Deleted Rows",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindByID(context.Background(), 7)
+				return err
+			},
+			wantQuery: "SELECT " + columns + " FROM `order` WHERE `id` = ? AND `deleted_at` IS NULL",
+			wantArgs:  []interface{}{7},
+		},
+		{
+			name: "FindWhere Renders Nested Filters",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindWhere(context.Background(), mySqlExt.And(
+					mySqlExt.Eq("user_id", 1),
+					mySqlExt.Or(mySqlExt.In("status", []string{"paid", "sent"}), mySqlExt.IsNull("status")),
+					mySqlExt.And(),
+				))
+				return err
+			},
+			wantQuery: "SELECT " + columns + " FROM `order` WHERE (`user_id` = ? AND (`status` IN (?) OR `status` IS NULL)) AND `deleted_at` IS NULL ORDER BY `id`",
+			wantArgs:  []interface{}{1, []string{"paid", "sent"}},
+		},
+		{
+			name: "FindWhere Without Filter",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindWhere(context.Background(), mySqlExt.Filter{})
+				return err
+			},
+			wantQuery: "SELECT " + columns + " FROM `order` WHERE 1 = 1 AND `deleted_at` IS NULL ORDER BY `id`",
+		},
+		{
+			name: "FindWhere Rejects Unknown Column",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindWhere(context.Background(), mySqlExt.Eq("status; DROP TABLE order", 1))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "FindPage After Cursor Descending",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindPage(context.Background(), mySqlExt.Eq("user_id", 1), mySqlExt.PageRequest{After: int64(50), Limit: 10, Desc: true})
+				return err
+			},
+			wantQuery: "SELECT " + columns + " FROM `order` WHERE (`user_id` = ? AND `id` < ?) AND `deleted_at` IS NULL ORDER BY `id` DESC LIMIT 11",
+			wantArgs:  []interface{}{1, int64(50)},
+		},
+		{
+			name: "FindPage Default Limit",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.FindPage(context.Background(), mySqlExt.Filter{}, mySqlExt.PageRequest{})
+				return err
+			},
+			wantQuery: "SELECT " + columns + " FROM `order` WHERE 1 = 1 AND `deleted_at` IS NULL ORDER BY `id` ASC LIMIT 21",
+		},
+		{
+			name: "SoftDelete",
+			run: func(r *mySqlExt.Repository[order]) error {
+				_, err := r.SoftDelete(context.Background(), 7)
+				return err
+			},
+			wantQuery: "UPDATE `order` SET `deleted_at` = NOW() WHERE `id` = ? AND `deleted_at` IS NULL",
+			wantArgs:  []interface{}{7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			err := tt.run(newOrderRepository(t, db))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				assertStatement(t, db, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRepositoryFindPageCursor(t *testing.T) {
+	db := &fakeDB{rows: []order{{ID: 1}, {ID: 2}, {ID: 3}}}
+	repository := newOrderRepository(t, db)
+
+	page, err := repository.FindPage(context.Background(), mySqlExt.Filter{}, mySqlExt.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("FindPage() error = %v", err)
+	}
+
+	if len(page.Items) != 2 || !page.HasMore || page.NextCursor != int64(2) {
+		t.Errorf("FindPage() = %+v, want 2 items, more and cursor 2", page)
+	}
+}
+
+func TestRepositoryInsert(t *testing.T) {
+	db := &fakeDB{result: fakeResult{lastInsertId: 42}}
+	repository := newOrderRepository(t, db)
+
+	entity := &order{UserID: 1, Status: "new"}
+	if _, err := repository.Insert(context.Background(), entity); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	assertStatement(t, db,
+		"INSERT INTO `order` (`user_id`, `status`, `version`, `deleted_at`) VALUES (?, ?, ?, ?)",
+		[]interface{}{int64(1), "new", int64(1), sql.NullTime{}},
+	)
+	if entity.ID != 42 || entity.Version != 1 {
+		t.Errorf("entity = %+v, want id 42 and version 1", entity)
+	}
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		affected    int64
+		wantErr     error
+		wantVersion int64
+	}{
+		{name: "Increments Version", affected: 1, wantVersion: 4},
+		{name: "Stale Version", affected: 0, wantErr: mySqlExt.ErrStaleVersion, wantVersion: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{result: fakeResult{rowsAffected: tt.affected}}
+			repository := newOrderRepository(t, db)
+
+			entity := &order{ID: 7, UserID: 1, Status: "paid", Version: 3}
+			_, err := repository.Update(context.Background(), entity)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+
+			assertStatement(t, db,
+				"UPDATE `order` SET `user_id` = ?, `status` = ?, `deleted_at` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ? AND `deleted_at` IS NULL",
+				[]interface{}{int64(1), "paid", sql.NullTime{}, int64(7), int64(3)},
+			)
+			if entity.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", entity.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestRepositorySoftDeleteWithoutColumn(t *testing.T) {
+	repository, err := mySqlExt.NewRepository[auditEntry](&fakeDB{})
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+
+	if _, err := repository.SoftDelete(context.Background(), "a"); !errors.Is(err, mySqlExt.ErrNoSoftDelete) {
+		t.Errorf("SoftDelete() error = %v, want ErrNoSoftDelete", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package sqlTest provides a database/sql driver answering statements from a handler, for
+// tests of code that needs a real *sqlx.DB, *sqlx.Tx or *sql.Rows.
+package sqlTest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Statements the driver records for transactions, next to the executed queries
+const (
+	Begin    = "BEGIN"
+	Commit   = "COMMIT"
+	Rollback = "ROLLBACK"
+)
+
+var errPrepareNotSupported = errors.New("sqlTest: prepared statements are not supported")
+
+// Result is the answer to a statement, Columns and Rows for queries and RowsAffected and
+// LastInsertId for everything else
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	LastInsertId int64
+}
+
+// Handler answers query, a nil handler answers every statement with an empty Result
+type Handler func(query string, args []driver.Value) (Result, error)
+
+// Statement is a query the driver received, with its arguments
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// DB records every statement sent to the database returned by Open
+type DB struct {
+	mu         sync.Mutex
+	handler    Handler
+	statements []Statement
+}
+
+// Open returns a database whose statements are answered by handler
+func Open(handler Handler) (*sqlx.DB, *DB) {
+	db := &DB{handler: handler}
+	return sqlx.NewDb(sql.OpenDB(connector{db}), "mysql"), db
+}
+
+// Statements returns the statements received so far, transactions included
+func (d *DB) Statements() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Statement(nil), d.statements...)
+}
+
+// Queries returns the query of every statement received so far
+func (d *DB) Queries() []string {
+	statements := d.Statements()
+
+	queries := make([]string, len(statements))
+	for i, statement := range statements {
+		queries[i] = statement.Query
+	}
+	return queries
+}
+
+func (d *DB) run(query string, args []driver.NamedValue) (Result, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	d.mu.Lock()
+	d.statements = append(d.statements, Statement{query, values})
+	d.mu.Unlock()
+
+	if d.handler == nil || query == Begin || query == Commit || query == Rollback {
+		return Result{}, nil
+	}
+	return d.handler(query, values)
+}
+
+type connector struct {
+	db *DB
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) { return &conn{c.db}, nil }
+func (c connector) Driver() driver.Driver                            { return nil }
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return nil, errPrepareNotSupported }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return c.BeginTx(context.Background(), driver.TxOptions{}) }
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.db.run(Begin, nil); err != nil {
+		return nil, err
+	}
+	return tx{c.db}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{result}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: result}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t tx) Commit() error {
+	_, err := t.db.run(Commit, nil)
+	return err
+}
+
+func (t tx) Rollback() error {
+	_, err := t.db.run(Rollback, nil)
+	return err
+}
+
+type execResult struct {
+	result Result
+}
+
+func (r execResult) LastInsertId() (int64, error) { return r.result.LastInsertId, nil }
+func (r execResult) RowsAffected() (int64, error) { return r.result.RowsAffected, nil }
+
+type rows struct {
+	result Result
+	next   int
+}
+
+func (r *rows) Columns() []string { return r.result.Columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
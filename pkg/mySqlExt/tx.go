@@ -0,0 +1,115 @@
+package mySqlExt
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/newRelicExt"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// executor is the subset of methods shared by *sqlx.DB and *sqlx.Tx
+type executor interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// txState is stored in the context while a transaction is running
+type txState struct {
+	tx    *sqlx.Tx
+	depth int
+}
+
+func getTxFromCtx(ctx context.Context) *txState {
+	if state, ok := ctx.Value(constant.CtxSQLTxKey).(*txState); ok {
+		return state
+	}
+	return nil
+}
+
+// InTx reports whether ctx carries a transaction started by WithTx
+func InTx(ctx context.Context) bool {
+	return getTxFromCtx(ctx) != nil
+}
+
+// executor returns the transaction stored in ctx, or the database when there is none
+func (m *mySqlExt) executor(ctx context.Context) executor {
+	if state := getTxFromCtx(ctx); state != nil {
+		return state.tx
+	}
+	return m.db
+}
+
+// WithTx runs fn inside a transaction. Every IMySqlExt call made with the ctx passed to fn
+// runs inside that transaction. The transaction is committed when fn returns nil and rolled
+// back when fn returns an error or panics. Nested calls use savepoints, opts is ignored for them.
+func (m *mySqlExt) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+	txn := newRelicExt.GetTxnFromCtx(ctx)
+
+	if parent := getTxFromCtx(ctx); parent != nil {
+		return m.withSavepoint(ctx, parent, fn)
+	}
+
+	dbSegment := newrelic.DatastoreSegment{
+		StartTime:  txn.StartSegmentNow(),
+		Product:    newrelic.DatastoreMySQL,
+		Collection: m.getTableName(ctx),
+		Operation:  "TRANSACTION",
+	}
+	defer dbSegment.End()
+
+	tx, err := m.db.BeginTxx(newrelic.NewContext(ctx, txn), opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	return fn(context.WithValue(ctx, constant.CtxSQLTxKey, &txState{tx: tx}))
+}
+
+func (m *mySqlExt) withSavepoint(ctx context.Context, parent *txState, fn func(ctx context.Context) error) (err error) {
+	state := &txState{tx: parent.tx, depth: parent.depth + 1}
+	savepoint := fmt.Sprintf("sp_%d", state.depth)
+
+	if _, err = state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+
+		if err != nil {
+			if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+				err = fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+			}
+			return
+		}
+
+		_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	}()
+
+	return fn(context.WithValue(ctx, constant.CtxSQLTxKey, state))
+}
@@ -0,0 +1,141 @@
+package mySqlExt
+
+import (
+	"boilerplate-service/pkg/mySqlExt/sqlTest"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name        string
+		fn          func(m *mySqlExt) func(ctx context.Context) error
+		wantErr     error
+		wantPanic   interface{}
+		wantQueries []string
+	}{
+		{
+			name: "Commit",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					_, err := m.ExecContext(ctx, "UPDATE a")
+					return err
+				}
+			},
+			wantQueries: []string{sqlTest.Begin, "UPDATE a", sqlTest.Commit},
+		},
+		{
+			name: "Rollback On Error",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					m.ExecContext(ctx, "UPDATE a")
+					return errFailed
+				}
+			},
+			wantErr:     errFailed,
+			wantQueries: []string{sqlTest.Begin, "UPDATE a", sqlTest.Rollback},
+		},
+		{
+			name: "Rollback On Panic",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					m.ExecContext(ctx, "UPDATE a")
+					panic("boom")
+				}
+			},
+			wantPanic:   "boom",
+			wantQueries: []string{sqlTest.Begin, "UPDATE a", sqlTest.Rollback},
+		},
+		{
+			name: "Nested Commit Releases The Savepoint",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return m.WithTx(ctx, nil, func(ctx context.Context) error {
+						_, err := m.ExecContext(ctx, "UPDATE b")
+						return err
+					})
+				}
+			},
+			wantQueries: []string{sqlTest.Begin, "SAVEPOINT sp_1", "UPDATE b", "RELEASE SAVEPOINT sp_1", sqlTest.Commit},
+		},
+		{
+			name: "Nested Error Rolls Back To The Savepoint Only",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					m.ExecContext(ctx, "UPDATE a")
+
+					err := m.WithTx(ctx, nil, func(ctx context.Context) error {
+						m.ExecContext(ctx, "UPDATE b")
+						return errFailed
+					})
+					if !errors.Is(err, errFailed) {
+						t.Errorf("nested WithTx() error = %v, want %v", err, errFailed)
+					}
+					return nil
+				}
+			},
+			wantQueries: []string{sqlTest.Begin, "UPDATE a", "SAVEPOINT sp_1", "UPDATE b", "ROLLBACK TO SAVEPOINT sp_1", sqlTest.Commit},
+		},
+		{
+			name: "Nested Panic Rolls Back Everything",
+			fn: func(m *mySqlExt) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					return m.WithTx(ctx, nil, func(ctx context.Context) error {
+						return m.WithTx(ctx, nil, func(ctx context.Context) error {
+							panic("boom")
+						})
+					})
+				}
+			},
+			wantPanic: "boom",
+			wantQueries: []string{
+				sqlTest.Begin, "SAVEPOINT sp_1", "SAVEPOINT sp_2",
+				"ROLLBACK TO SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_1", sqlTest.Rollback,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := sqlTest.Open(nil)
+			m := &mySqlExt{db: db}
+
+			var err error
+			panicked := func() (p interface{}) {
+				defer func() { p = recover() }()
+				err = m.WithTx(context.Background(), nil, tt.fn(m))
+				return nil
+			}()
+
+			if panicked != tt.wantPanic {
+				t.Errorf("panic = %v, want %v", panicked, tt.wantPanic)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if queries := recorder.Queries(); !reflect.DeepEqual(queries, tt.wantQueries) {
+				t.Errorf("queries = %q\nwant      %q", queries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestInTx(t *testing.T) {
+	db, _ := sqlTest.Open(nil)
+	m := &mySqlExt{db: db}
+
+	if InTx(context.Background()) {
+		t.Error("InTx() = true outside WithTx")
+	}
+
+	m.WithTx(context.Background(), nil, func(ctx context.Context) error {
+		if !InTx(ctx) {
+			t.Error("InTx() = false inside WithTx")
+		}
+		return nil
+	})
+}
@@ -3,9 +3,11 @@ package newRelicExt
 import (
 	"boilerplate-service/constant"
 	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/util"
 	"context"
 	"errors"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/newrelic/go-agent/v3/integrations/nrzap"
@@ -19,6 +21,9 @@ type INewRelicExt interface {
 	RecordCustomMetric(name string, value float64)
 	Shutdown(timeout time.Duration)
 	StartTransaction(name string, opts ...newrelic.TraceOption) *newrelic.Transaction
+	// AddAttribute adds a custom attribute to txn, string values go through Config.Redactor and
+	// attributes named after a redacted field are dropped
+	AddAttribute(txn *newrelic.Transaction, key string, value interface{})
 }
 
 type Config struct {
@@ -27,10 +32,15 @@ type Config struct {
 	ServiceName string
 
 	Logger logger.ILogger
+	// Redactor lists the headers and fields excluded from span attributes and redacts the values
+	// given to AddAttribute. Attributes the agent collects by itself are only filtered by name,
+	// their values are sent as is.
+	Redactor *util.Redactor
 }
 
 type newRelicExt struct {
-	app *newrelic.Application
+	app      *newrelic.Application
+	redactor *util.Redactor
 }
 
 const (
@@ -64,7 +74,7 @@ func New(config Config) (INewRelicExt, error) {
 		// If not development
 		if config.Environment != constant.EnvironmentLocal && config.Environment != constant.EnvironmentDevelopment {
 			cfg.SpanEvents.Enabled = true
-			cfg.SpanEvents.Attributes.Exclude = excludingAttrSpans()
+			cfg.SpanEvents.Attributes.Exclude = excludingAttrSpans(config.Redactor)
 
 			cfg.DatastoreTracer.InstanceReporting.Enabled = true
 			cfg.DatastoreTracer.DatabaseNameReporting.Enabled = true
@@ -77,7 +87,7 @@ func New(config Config) (INewRelicExt, error) {
 	})
 
 	app, err := newrelic.NewApplication(options...)
-	return &newRelicExt{app}, err
+	return &newRelicExt{app, config.Redactor}, err
 }
 
 func (n *newRelicExt) App() *newrelic.Application {
@@ -100,20 +110,54 @@ func (n *newRelicExt) StartTransaction(name string, opts ...newrelic.TraceOption
 	return n.app.StartTransaction(name, opts...)
 }
 
+func (n *newRelicExt) AddAttribute(txn *newrelic.Transaction, key string, value interface{}) {
+	if value, ok := redactAttribute(n.redactor, key, value); ok {
+		txn.AddAttribute(key, value)
+	}
+}
+
+// GetTxnFromCtx returns the transaction stored under constant.CtxNewRelicTxnKey, or by
+// newrelic.NewContext as the HTTP middleware does
 func GetTxnFromCtx(ctx context.Context) *newrelic.Transaction {
 	if txn := ctx.Value(constant.CtxNewRelicTxnKey); txn != nil {
 		return txn.(*newrelic.Transaction)
 	}
-	return nil
+	return newrelic.FromContext(ctx)
 }
 
 // excludingAttrSpans returns a list of attributes to exclude to shown in NR spans
-// By service requirements
+// By service requirements, plus the headers and fields hidden by redactor
 //
-// No parameters.
 // Returns a slice of strings.
-func excludingAttrSpans() []string {
-	return []string{
+func excludingAttrSpans(redactor *util.Redactor) []string {
+	attributes := []string{
 		"password",
 	}
+
+	for _, header := range redactor.HeaderNames() {
+		attributes = append(attributes, "request.headers."+strings.ToLower(header))
+	}
+
+	for _, field := range redactor.FieldNames() {
+		attributes = append(attributes, field, "request.parameters."+field, "message.parameters."+field)
+	}
+
+	return attributes
+}
+
+// redactAttribute returns the value of attribute key to send, ok is false when the attribute
+// must not be sent at all
+func redactAttribute(redactor *util.Redactor, key string, value interface{}) (redacted interface{}, ok bool) {
+	name := key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		name = key[i+1:]
+	}
+	if redactor.IsField(name) {
+		return nil, false
+	}
+
+	if s, isString := value.(string); isString {
+		return redactor.String(s), true
+	}
+	return value, true
 }
@@ -0,0 +1,83 @@
+package newRelicExt
+
+import (
+	"boilerplate-service/pkg/util"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func newTestRedactor(t *testing.T) *util.Redactor {
+	t.Helper()
+
+	redactor, err := util.NewRedactor(util.RedactionConfig{
+		Headers:  []string{"Authorization"},
+		Fields:   []string{"card_number"},
+		Patterns: util.DefaultRedactionConfig().Patterns,
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	return redactor
+}
+
+func TestExcludingAttrSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		redactor *util.Redactor
+		want     []string
+	}{
+		{
+			name:     "Without Redactor",
+			redactor: nil,
+			want:     []string{"password"},
+		},
+		{
+			name:     "Redacted Headers And Fields",
+			redactor: newTestRedactor(t),
+			want: []string{
+				"card_number",
+				"message.parameters.card_number",
+				"password",
+				"request.headers.authorization",
+				"request.parameters.card_number",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := excludingAttrSpans(tt.redactor)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("excludingAttrSpans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactAttribute(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	tests := []struct {
+		name   string
+		key    string
+		value  interface{}
+		want   interface{}
+		wantOk bool
+	}{
+		{name: "Plain Value", key: "message.queueName", value: "orders", want: "orders", wantOk: true},
+		{name: "Card In Value", key: "message.routingKey", value: "card.4111111111111111", want: "card.411111******1111", wantOk: true},
+		{name: "Redacted Field", key: "message.parameters.card_number", value: "4111111111111111", wantOk: false},
+		{name: "Not A String", key: "message.size", value: 42, want: 42, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := redactAttribute(redactor, tt.key, tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("redactAttribute() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
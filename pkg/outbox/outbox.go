@@ -0,0 +1,154 @@
+package outbox
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/mySqlExt"
+	"boilerplate-service/pkg/rabbitMQExt"
+	"boilerplate-service/pkg/traceContext"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNoTransaction = errors.New("outbox record must run inside mySqlExt.WithTx")
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+type IOutbox interface {
+	// Record stores events in the outbox table. ctx must come from mySqlExt.WithTx so the
+	// events are committed or rolled back together with the caller's writes.
+	Record(ctx context.Context, events ...Event) error
+	// Relay publishes pending events until ctx is done
+	Relay(ctx context.Context) error
+}
+
+// Event is a message to publish once the surrounding transaction commits
+type Event struct {
+	MessageID  string
+	Exchange   string
+	RoutingKey string
+	Headers    map[string]interface{}
+	Payload    []byte
+}
+
+type Config struct {
+	TableName string
+	// PollInterval is how often the relay looks for pending events
+	PollInterval time.Duration
+	// BatchSize is the number of events locked and published per poll
+	BatchSize int
+	// MaxAttempts marks an event as failed after that many publish errors
+	MaxAttempts int
+	// RetryDelay is the base of the exponential backoff between publish attempts
+	RetryDelay time.Duration
+	// Retention is how long sent events are kept before cleanup, 0 disables cleanup
+	Retention       time.Duration
+	CleanupInterval time.Duration
+
+	Logger logger.ILogger
+}
+
+type outbox struct {
+	db        mySqlExt.IMySqlExt
+	publisher rabbitMQExt.IRabbitMQExt
+	config    Config
+}
+
+const (
+	defaultTableName       = "outbox"
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultMaxAttempts     = 10
+	defaultRetryDelay      = time.Second
+	defaultCleanupInterval = time.Hour
+)
+
+// New returns an outbox writing to config.TableName. publisher is only needed by Relay
+// and may be nil for services that only record events.
+func New(db mySqlExt.IMySqlExt, publisher rabbitMQExt.IRabbitMQExt, config Config) IOutbox {
+	if config.TableName == "" {
+		config.TableName = defaultTableName
+	}
+
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultPollInterval
+	}
+
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBatchSize
+	}
+
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultRetryDelay
+	}
+
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = defaultCleanupInterval
+	}
+
+	return &outbox{db, publisher, config}
+}
+
+func (o *outbox) withTable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, constant.CtxSQLTableNameKey, o.config.TableName)
+}
+
+func (o *outbox) Record(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if !mySqlExt.InTx(ctx) {
+		return ErrNoTransaction
+	}
+
+	placeholders := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*5)
+	for _, event := range events {
+		if event.MessageID == "" {
+			event.MessageID = uuid.New().String()
+		}
+
+		headers := map[string]interface{}{}
+		for k, v := range event.Headers {
+			headers[k] = v
+		}
+		// The relay publishes later without this ctx, the trace context travels in the headers
+		if trace, ok := traceContext.FromContext(ctx); ok {
+			for k, v := range trace.Headers() {
+				headers[k] = v
+			}
+		}
+
+		rawHeaders, err := json.Marshal(headers)
+		if err != nil {
+			return err
+		}
+
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		args = append(args, event.MessageID, event.Exchange, event.RoutingKey, rawHeaders, event.Payload)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO `%s` (`message_id`, `exchange`, `routing_key`, `headers`, `payload`) VALUES %s",
+		o.config.TableName,
+		strings.Join(placeholders, ", "),
+	)
+
+	_, err := o.db.ExecContext(o.withTable(ctx), query, args...)
+	return err
+}
@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"boilerplate-service/pkg/rabbitMQExt"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+type record struct {
+	ID         int64  `db:"id"`
+	MessageID  string `db:"message_id"`
+	Exchange   string `db:"exchange"`
+	RoutingKey string `db:"routing_key"`
+	Headers    []byte `db:"headers"`
+	Payload    []byte `db:"payload"`
+	Attempts   int    `db:"attempts"`
+}
+
+const (
+	maxRetryDelay   = time.Hour
+	cleanupBatchMax = 1000
+)
+
+// Relay polls the outbox and publishes pending events, oldest first. Each batch holds a MySQL
+// named lock, so when several pods run a relay only one publishes and the others stand by.
+//
+// Events sharing an exchange and routing key go out in the order they were recorded. An event
+// that fails to publish holds back the later ones of its key until it is sent, or until it is
+// marked failed after MaxAttempts. Events of other keys keep flowing.
+func (o *outbox) Relay(ctx context.Context) error {
+	if o.publisher == nil {
+		return errors.New("outbox relay requires a publisher")
+	}
+
+	poll := time.NewTicker(o.config.PollInterval)
+	defer poll.Stop()
+
+	var cleanup <-chan time.Time
+	if o.config.Retention > 0 {
+		ticker := time.NewTicker(o.config.CleanupInterval)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+
+	for {
+		// Keep going while batches come back full so a backlog drains without waiting for the ticker
+		for ctx.Err() == nil {
+			// A batch that started is finished even when ctx is cancelled, so no row is left half-published
+			published, err := o.relayBatch(context.WithoutCancel(ctx))
+			if err != nil {
+				o.logError(ctx, "Outbox relay batch failed", err)
+				break
+			}
+			if published < o.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		case <-cleanup:
+			if err := o.cleanup(ctx); err != nil {
+				o.logError(ctx, "Outbox cleanup failed", err)
+			}
+		}
+	}
+}
+
+// relayBatch publishes one locked batch and returns how many events were sent. It returns 0
+// without publishing when another relay holds the lock.
+func (o *outbox) relayBatch(ctx context.Context) (int, error) {
+	published := 0
+
+	err := o.db.WithTx(o.withTable(ctx), nil, func(ctx context.Context) error {
+		var acquired sql.NullInt64
+		err := o.db.GetContext(ctx, &acquired, "SELECT GET_LOCK(CONCAT(DATABASE(), '.', ?), 0)", o.config.TableName)
+		if err != nil {
+			return err
+		}
+		if !acquired.Valid || acquired.Int64 != 1 {
+			return nil
+		}
+		// The rows stay locked until commit, the next relay to get the lock waits for them
+		defer o.db.ExecContext(ctx, "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.', ?))", o.config.TableName)
+
+		// Events behind a retry of their exchange and routing key wait for it
+		var records []record
+		err = o.db.SelectContext(ctx, &records, fmt.Sprintf(
+			"SELECT `id`, `message_id`, `exchange`, `routing_key`, `headers`, `payload`, `attempts` FROM `%[1]s` AS `o` "+
+				"WHERE `status` = ? AND `available_at` <= NOW(6) AND NOT EXISTS ("+
+				"SELECT 1 FROM `%[1]s` AS `r` WHERE `r`.`status` = ? AND `r`.`exchange` = `o`.`exchange` "+
+				"AND `r`.`routing_key` = `o`.`routing_key` AND `r`.`id` < `o`.`id` AND `r`.`available_at` > NOW(6)"+
+				") ORDER BY `id` LIMIT ? FOR UPDATE",
+			o.config.TableName,
+		), StatusPending, StatusPending, o.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		sentIds := make([]int64, 0, len(records))
+		failedKeys := map[[2]string]bool{}
+		for _, rec := range records {
+			key := [2]string{rec.Exchange, rec.RoutingKey}
+			if failedKeys[key] {
+				continue
+			}
+
+			if err := o.publish(ctx, rec); err != nil {
+				o.logError(ctx, "Outbox publish failed", err, zap.String("message_id", rec.MessageID))
+				if err := o.markFailedAttempt(ctx, rec, err); err != nil {
+					return err
+				}
+				failedKeys[key] = true
+				continue
+			}
+			sentIds = append(sentIds, rec.ID)
+		}
+
+		if len(sentIds) == 0 {
+			return nil
+		}
+
+		_, err = o.db.ExecContext(ctx, fmt.Sprintf(
+			"UPDATE `%s` SET `status` = ?, `sent_at` = NOW(6), `attempts` = `attempts` + 1 WHERE `id` IN (?)",
+			o.config.TableName,
+		), StatusSent, sentIds)
+		if err != nil {
+			return err
+		}
+
+		published = len(sentIds)
+		return nil
+	})
+
+	return published, err
+}
+
+func (o *outbox) publish(ctx context.Context, rec record) error {
+	headers := amqp.Table{}
+	if len(rec.Headers) > 0 {
+		if err := json.Unmarshal(rec.Headers, &headers); err != nil {
+			return err
+		}
+	}
+
+	return o.publisher.Publish(ctx, rec.Exchange, rec.RoutingKey, rabbitMQExt.Message{
+		MessageID: rec.MessageID,
+		Headers:   headers,
+		Body:      rec.Payload,
+	})
+}
+
+// markFailedAttempt schedules the next attempt with exponential backoff,
+// or marks the event failed once MaxAttempts is reached.
+func (o *outbox) markFailedAttempt(ctx context.Context, rec record, publishErr error) error {
+	attempts := rec.Attempts + 1
+
+	status := StatusPending
+	if attempts >= o.config.MaxAttempts {
+		status = StatusFailed
+	}
+
+	delay := o.config.RetryDelay << (attempts - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE `%s` SET `status` = ?, `attempts` = ?, `last_error` = ?, "+
+			"`available_at` = NOW(6) + INTERVAL ? MICROSECOND WHERE `id` = ?",
+		o.config.TableName,
+	), status, attempts, publishErr.Error(), delay.Microseconds(), rec.ID)
+
+	return err
+}
+
+// cleanup deletes sent events older than Retention in small batches to keep locks short
+func (o *outbox) cleanup(ctx context.Context) error {
+	for ctx.Err() == nil {
+		result, err := o.db.ExecContext(o.withTable(ctx), fmt.Sprintf(
+			"DELETE FROM `%s` WHERE `status` = ? AND `sent_at` < NOW(6) - INTERVAL ? SECOND LIMIT %d",
+			o.config.TableName, cleanupBatchMax,
+		), StatusSent, int64(o.config.Retention.Seconds()))
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil || affected < cleanupBatchMax {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *outbox) logError(ctx context.Context, msg string, err error, fields ...zap.Field) {
+	if o.config.Logger != nil {
+		o.config.Logger.Error(ctx, msg, append(fields, zap.Error(err))...)
+	}
+}
@@ -0,0 +1,228 @@
+package outbox
+
+import (
+	"boilerplate-service/pkg/mySqlExt"
+	"boilerplate-service/pkg/mySqlExt/sqlTest"
+	"boilerplate-service/pkg/rabbitMQExt"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakePublisher struct {
+	rabbitMQExt.IRabbitMQExt
+
+	errs      map[string]error
+	published []rabbitMQExt.Message
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, exchange, routingKey string, msg rabbitMQExt.Message) error {
+	p.published = append(p.published, msg)
+	return p.errs[msg.MessageID]
+}
+
+func (p *fakePublisher) messageIds() []string {
+	ids := []string{}
+	for _, msg := range p.published {
+		ids = append(ids, msg.MessageID)
+	}
+	return ids
+}
+
+// newTestOutbox answers the lock with lock and the claim with records
+func newTestOutbox(publisher *fakePublisher, lock int64, records ...record) (*outbox, *sqlTest.DB) {
+	db, recorder := sqlTest.Open(func(query string, args []driver.Value) (sqlTest.Result, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT GET_LOCK"):
+			return sqlTest.Result{Columns: []string{"lock"}, Rows: [][]driver.Value{{lock}}}, nil
+
+		case strings.HasPrefix(query, "SELECT `id`"):
+			result := sqlTest.Result{Columns: []string{"id", "message_id", "exchange", "routing_key", "headers", "payload", "attempts"}}
+			for _, rec := range records {
+				result.Rows = append(result.Rows, []driver.Value{
+					rec.ID, rec.MessageID, rec.Exchange, rec.RoutingKey, rec.Headers, rec.Payload, int64(rec.Attempts),
+				})
+			}
+			return result, nil
+		}
+		return sqlTest.Result{}, nil
+	})
+
+	o := New(mySqlExt.NewWithDB(db), publisher, Config{MaxAttempts: 3, RetryDelay: time.Second}).(*outbox)
+	return o, recorder
+}
+
+// statements returns the statements whose query starts with prefix
+func statements(recorder *sqlTest.DB, prefix string) []sqlTest.Statement {
+	found := []sqlTest.Statement{}
+	for _, statement := range recorder.Statements() {
+		if strings.HasPrefix(statement.Query, prefix) {
+			found = append(found, statement)
+		}
+	}
+	return found
+}
+
+func TestRelayBatch(t *testing.T) {
+	errBroker := errors.New("broker down")
+	records := []record{
+		{ID: 1, MessageID: "m1", Exchange: "orders", RoutingKey: "order.created", Headers: []byte(`{"x-request-id":"req-1"}`)},
+		{ID: 2, MessageID: "m2", Exchange: "orders", RoutingKey: "order.paid"},
+		{ID: 3, MessageID: "m3", Exchange: "orders", RoutingKey: "order.created", Attempts: 2},
+	}
+
+	tests := []struct {
+		name          string
+		lock          int64
+		errs          map[string]error
+		wantPublished []string
+		// wantRetries are the status and attempts of each failed publish
+		wantRetries [][]driver.Value
+		wantSent    []driver.Value
+	}{
+		{
+			name:          "Publishes In Order",
+			lock:          1,
+			wantPublished: []string{"m1", "m2", "m3"},
+			wantSent:      []driver.Value{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:          "Failure Holds Back Its Key Only",
+			lock:          1,
+			errs:          map[string]error{"m1": errBroker},
+			wantPublished: []string{"m1", "m2"},
+			wantRetries:   [][]driver.Value{{StatusPending, int64(1)}},
+			wantSent:      []driver.Value{int64(2)},
+		},
+		{
+			name:          "Last Attempt Marks The Event Failed",
+			lock:          1,
+			errs:          map[string]error{"m3": errBroker},
+			wantPublished: []string{"m1", "m2", "m3"},
+			wantRetries:   [][]driver.Value{{StatusFailed, int64(3)}},
+			wantSent:      []driver.Value{int64(1), int64(2)},
+		},
+		{
+			name:          "Another Relay Holds The Lock",
+			lock:          0,
+			wantPublished: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{errs: tt.errs}
+			o, recorder := newTestOutbox(publisher, tt.lock, records...)
+
+			published, err := o.relayBatch(context.Background())
+			if err != nil {
+				t.Fatalf("relayBatch() error = %v", err)
+			}
+			if published != len(tt.wantSent) {
+				t.Errorf("relayBatch() = %d, want %d", published, len(tt.wantSent))
+			}
+			if ids := publisher.messageIds(); !reflect.DeepEqual(ids, tt.wantPublished) {
+				t.Errorf("published = %v, want %v", ids, tt.wantPublished)
+			}
+			if len(publisher.published) > 0 && publisher.published[0].Headers["x-request-id"] != "req-1" {
+				t.Errorf("headers = %v, want the recorded headers", publisher.published[0].Headers)
+			}
+
+			if claims := statements(recorder, "SELECT `id`"); len(claims) != int(tt.lock) {
+				t.Errorf("claims = %d, want %d", len(claims), tt.lock)
+			} else if len(claims) > 0 && !strings.Contains(claims[0].Query, "NOT EXISTS") {
+				t.Errorf("claim = %q, want the events behind a retry skipped", claims[0].Query)
+			}
+
+			var retries [][]driver.Value
+			for _, statement := range statements(recorder, "UPDATE `outbox` SET `status` = ?, `attempts` = ?") {
+				retries = append(retries, statement.Args[:2])
+			}
+			if !reflect.DeepEqual(retries, tt.wantRetries) {
+				t.Errorf("retries = %v, want %v", retries, tt.wantRetries)
+			}
+
+			sent := statements(recorder, "UPDATE `outbox` SET `status` = ?, `sent_at`")
+			if len(tt.wantSent) == 0 {
+				if len(sent) != 0 {
+					t.Errorf("sent = %v, want none", sent)
+				}
+			} else if len(sent) != 1 || !reflect.DeepEqual(sent[0].Args[1:], tt.wantSent) {
+				t.Errorf("sent = %v, want ids %v", sent, tt.wantSent)
+			}
+
+			if released := statements(recorder, "SELECT RELEASE_LOCK"); len(released) != int(tt.lock) {
+				t.Errorf("released = %d, want %d", len(released), tt.lock)
+			}
+			if queries := recorder.Queries(); queries[len(queries)-1] != sqlTest.Commit {
+				t.Errorf("last statement = %q, want a commit", queries[len(queries)-1])
+			}
+		})
+	}
+}
+
+func TestMarkFailedAttemptBackoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		wantDelay time.Duration
+	}{
+		{name: "First Retry", attempts: 0, wantDelay: time.Second},
+		{name: "Doubles", attempts: 2, wantDelay: 4 * time.Second},
+		{name: "Capped", attempts: 40, wantDelay: maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, recorder := newTestOutbox(&fakePublisher{}, 1)
+			o.config.MaxAttempts = 100
+
+			if err := o.markFailedAttempt(context.Background(), record{ID: 7, Attempts: tt.attempts}, errors.New("broker down")); err != nil {
+				t.Fatalf("markFailedAttempt() error = %v", err)
+			}
+
+			args := recorder.Statements()[0].Args
+			if args[2] != "broker down" || args[3] != tt.wantDelay.Microseconds() || args[4] != int64(7) {
+				t.Errorf("args = %v, want a %v delay", args, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	deletes := 0
+	db, recorder := sqlTest.Open(func(query string, args []driver.Value) (sqlTest.Result, error) {
+		deletes++
+		// The first batch is full, the second one finishes the cleanup
+		if deletes == 1 {
+			return sqlTest.Result{RowsAffected: cleanupBatchMax}, nil
+		}
+		return sqlTest.Result{RowsAffected: 3}, nil
+	})
+	o := New(mySqlExt.NewWithDB(db), nil, Config{Retention: 48 * time.Hour}).(*outbox)
+
+	if err := o.cleanup(context.Background()); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+
+	statements := recorder.Statements()
+	if len(statements) != 2 {
+		t.Fatalf("statements = %v, want 2 deletes", statements)
+	}
+	for _, statement := range statements {
+		if !strings.HasPrefix(statement.Query, "DELETE FROM `outbox`") || statement.Args[0] != StatusSent || statement.Args[1] != int64(48*60*60) {
+			t.Errorf("statement = %v, want sent events older than 48h deleted", statement)
+		}
+	}
+}
+
+func TestRelayRequiresPublisher(t *testing.T) {
+	db, _ := sqlTest.Open(nil)
+	if err := New(mySqlExt.NewWithDB(db), nil, Config{}).Relay(context.Background()); err == nil {
+		t.Error("Relay() error = nil, want an error without a publisher")
+	}
+}
@@ -0,0 +1,290 @@
+package rabbitMQExt
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/traceContext"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// Handler processes one delivery. Returning nil acks the message, returning an
+// error rejects it to the dead-letter exchange, and returning Requeue(err)
+// puts it back on the queue.
+type Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+type ConsumerConfig struct {
+	Queue string
+	// Exchange and RoutingKeys bind Queue when set, the exchange is declared with ExchangeType (default "topic")
+	Exchange     string
+	ExchangeType string
+	RoutingKeys  []string
+	// DeadLetterExchange receives rejected messages into "<Queue>.dlq", "" disables dead-lettering
+	DeadLetterExchange string
+	// RetryDelay parks requeued messages in "<Queue>.retry" for this long before they come back,
+	// 0 puts them straight back on the queue
+	RetryDelay time.Duration
+
+	// Prefetch is the number of unacked messages the broker pushes, defaults to Concurrency
+	Prefetch int
+	// Concurrency is the number of handlers running in parallel, defaults to 1
+	Concurrency int
+}
+
+type requeueError struct {
+	err error
+}
+
+func (e *requeueError) Error() string { return e.err.Error() }
+func (e *requeueError) Unwrap() error { return e.err }
+
+// Requeue wraps err so the delivery is nacked back onto the queue instead of dead-lettered
+func Requeue(err error) error {
+	return &requeueError{err}
+}
+
+func (r *rabbitMQExt) Consume(ctx context.Context, config ConsumerConfig, handler Handler) error {
+	if config.Queue == "" {
+		return errors.New("rabbitmq consumer queue is empty")
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+
+	if config.Prefetch < config.Concurrency {
+		config.Prefetch = config.Concurrency
+	}
+
+	if config.ExchangeType == "" {
+		config.ExchangeType = amqp.ExchangeTopic
+	}
+
+	for {
+		err := r.consume(ctx, config, handler)
+		if ctx.Err() != nil || r.isClosed() {
+			return nil
+		}
+
+		r.logWarn("RabbitMQ consumer stopped, restarting", zap.String("queue", config.Queue), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.config.ReconnectDelay):
+		}
+	}
+}
+
+// consume runs one channel's worth of consumption, returning when the channel
+// closes or, after ctx is done, when every in-flight delivery is handled.
+func (r *rabbitMQExt) consume(ctx context.Context, config ConsumerConfig, handler Handler) error {
+	ch, err := r.channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(config.Prefetch, 0, false); err != nil {
+		return err
+	}
+
+	if err := declareTopology(ch, config); err != nil {
+		return err
+	}
+
+	tag := fmt.Sprintf("%s-%s", config.Queue, uuid.New().String())
+	deliveries, err := ch.Consume(config.Queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	// Handlers keep running past ctx cancellation so in-flight messages are drained and acked
+	handlerCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range deliveries {
+				r.handle(handlerCtx, config, handler, delivery)
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Stops new deliveries, the deliveries channel closes once buffered ones are handed out
+			ch.Cancel(tag, false)
+		case <-stopped:
+		}
+	}()
+
+	wg.Wait()
+	close(stopped)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return ErrClosed
+}
+
+func (r *rabbitMQExt) handle(ctx context.Context, config ConsumerConfig, handler Handler, delivery amqp.Delivery) {
+	trace := extractTraceContext(delivery)
+
+	var txn *newrelic.Transaction
+	if r.config.NewRelic != nil {
+		txn = r.config.NewRelic.StartTransaction("RabbitMQ/Consume/" + config.Queue)
+		defer txn.End()
+
+		txn.AcceptDistributedTraceHeaders(newrelic.TransportAMQP, toHttpHeader(delivery.Headers))
+		r.config.NewRelic.AddAttribute(txn, "message.queueName", config.Queue)
+		r.config.NewRelic.AddAttribute(txn, "message.routingKey", delivery.RoutingKey)
+
+		ctx = context.WithValue(ctx, constant.CtxNewRelicTxnKey, txn)
+		ctx = newrelic.NewContext(ctx, txn)
+
+		trace = trace.WithNewRelic(txn)
+
+		segment := txn.StartSegment("MessageBroker/RabbitMQ/Queue/Consume/Named/" + config.Queue)
+		defer segment.End()
+	}
+	ctx = traceContext.NewContext(ctx, trace)
+
+	err := r.runHandler(ctx, handler, delivery)
+
+	var requeue *requeueError
+	switch {
+	case err == nil:
+		if ackErr := delivery.Ack(false); ackErr != nil {
+			r.logError(ctx, "RabbitMQ ack failed", config, delivery, ackErr)
+		}
+	case errors.As(err, &requeue):
+		txn.NoticeError(err)
+		r.logError(ctx, "RabbitMQ handler failed, requeueing", config, delivery, err)
+		r.requeue(ctx, config, delivery)
+	default:
+		txn.NoticeError(err)
+		r.logError(ctx, "RabbitMQ handler failed, rejecting", config, delivery, err)
+		delivery.Nack(false, false)
+	}
+}
+
+// requeue sends delivery through the retry queue when RetryDelay is set, so a message that
+// can't be handled yet doesn't spin between the queue and the consumer
+func (r *rabbitMQExt) requeue(ctx context.Context, config ConsumerConfig, delivery amqp.Delivery) {
+	if config.RetryDelay <= 0 {
+		delivery.Nack(false, true)
+		return
+	}
+
+	err := r.Publish(ctx, "", retryQueue(config), Message{
+		MessageID:   delivery.MessageId,
+		ContentType: delivery.ContentType,
+		Headers:     delivery.Headers,
+		Body:        delivery.Body,
+		Transient:   delivery.DeliveryMode == amqp.Transient,
+	})
+	if err != nil {
+		r.logError(ctx, "RabbitMQ retry publish failed, requeueing immediately", config, delivery, err)
+		delivery.Nack(false, true)
+		return
+	}
+
+	if ackErr := delivery.Ack(false); ackErr != nil {
+		r.logError(ctx, "RabbitMQ ack failed", config, delivery, ackErr)
+	}
+}
+
+func retryQueue(config ConsumerConfig) string {
+	return config.Queue + ".retry"
+}
+
+// runHandler converts a handler panic into an error so the delivery is rejected instead of lost
+func (r *rabbitMQExt) runHandler(ctx context.Context, handler Handler, delivery amqp.Delivery) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panic: %v", p)
+		}
+	}()
+
+	return handler(ctx, delivery)
+}
+
+func (r *rabbitMQExt) logError(ctx context.Context, msg string, config ConsumerConfig, delivery amqp.Delivery, err error) {
+	if r.config.Logger == nil {
+		return
+	}
+
+	r.config.Logger.Error(
+		ctx,
+		msg,
+		zap.String("queue", config.Queue),
+		zap.String("message_id", delivery.MessageId),
+		zap.Bool("redelivered", delivery.Redelivered),
+		zap.Error(err),
+	)
+}
+
+// declareTopology declares the queue, its bindings, dead-letter and retry queues. Declarations are idempotent.
+func declareTopology(ch *amqp.Channel, config ConsumerConfig) error {
+	if config.RetryDelay > 0 {
+		// Expired messages are dead-lettered through the default exchange back onto Queue
+		retryArgs := amqp.Table{
+			"x-message-ttl":             config.RetryDelay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": config.Queue,
+		}
+		if _, err := ch.QueueDeclare(retryQueue(config), true, false, false, false, retryArgs); err != nil {
+			return err
+		}
+	}
+
+	args := amqp.Table{}
+	if config.DeadLetterExchange != "" {
+		dlq := config.Queue + ".dlq"
+
+		if err := ch.ExchangeDeclare(config.DeadLetterExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+			return err
+		}
+		if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+			return err
+		}
+		if err := ch.QueueBind(dlq, config.Queue, config.DeadLetterExchange, false, nil); err != nil {
+			return err
+		}
+
+		args["x-dead-letter-exchange"] = config.DeadLetterExchange
+		args["x-dead-letter-routing-key"] = config.Queue
+	}
+
+	if _, err := ch.QueueDeclare(config.Queue, true, false, false, false, args); err != nil {
+		return err
+	}
+
+	if config.Exchange == "" {
+		return nil
+	}
+
+	if err := ch.ExchangeDeclare(config.Exchange, config.ExchangeType, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	for _, key := range config.RoutingKeys {
+		if err := ch.QueueBind(config.Queue, key, config.Exchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package rabbitMQExt
+
+import (
+	"boilerplate-service/pkg/traceContext"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// injectTraceHeaders copies the trace context from ctx into message headers
+func injectTraceHeaders(ctx context.Context, headers amqp.Table) {
+	if trace, ok := traceContext.FromContext(ctx); ok {
+		for k, v := range trace.Headers() {
+			headers[k] = v
+		}
+	}
+}
+
+// injectNewRelicHeaders copies the distributed tracing headers of New Relic into message headers.
+// They are written lowercase so New Relic's traceparent and tracestate replace ours instead of
+// travelling next to them under a second spelling.
+func injectNewRelicHeaders(dtHeaders http.Header, headers amqp.Table) {
+	for k := range dtHeaders {
+		headers[strings.ToLower(k)] = dtHeaders.Get(k)
+	}
+}
+
+// extractTraceContext returns the trace context from message headers. Messages without one
+// start a trace whose request id is the message id.
+func extractTraceContext(delivery amqp.Delivery) traceContext.TraceContext {
+	trace, ok := traceContext.Extract(toHttpHeader(delivery.Headers))
+	if !ok && delivery.MessageId != "" {
+		return traceContext.FromRequestId(delivery.MessageId)
+	}
+	return trace
+}
+
+// toHttpHeader converts string message headers for New Relic distributed tracing
+func toHttpHeader(headers amqp.Table) http.Header {
+	httpHeader := http.Header{}
+	for k, v := range headers {
+		switch value := v.(type) {
+		case string:
+			httpHeader.Set(k, value)
+		case []byte:
+			httpHeader.Set(k, string(value))
+		default:
+			httpHeader.Set(k, fmt.Sprint(value))
+		}
+	}
+	return httpHeader
+}
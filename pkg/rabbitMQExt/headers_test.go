@@ -0,0 +1,45 @@
+package rabbitMQExt
+
+import (
+	"boilerplate-service/pkg/traceContext"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestInjectNewRelicHeaders(t *testing.T) {
+	trace, err := traceContext.Parse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	trace.TraceState = "congo=t61rcWkgMzE"
+	trace.RequestId = "req-1"
+
+	headers := amqp.Table{}
+	injectTraceHeaders(traceContext.NewContext(context.Background(), trace), headers)
+
+	// InsertDistributedTraceHeaders writes canonical keys
+	dtHeaders := http.Header{}
+	dtHeaders.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01")
+	dtHeaders.Set("Tracestate", "nr=1")
+	dtHeaders.Set("Newrelic", "eyJ2IjpbMCwxXX0=")
+	injectNewRelicHeaders(dtHeaders, headers)
+
+	want := amqp.Table{
+		traceContext.HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-1111111111111111-01",
+		traceContext.HeaderTracestate:  "nr=1",
+		traceContext.HeaderRequestId:   "req-1",
+		"newrelic":                     "eyJ2IjpbMCwxXX0=",
+	}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("headers = %v, want %v", headers, want)
+	}
+
+	extracted := extractTraceContext(amqp.Delivery{Headers: headers})
+	if extracted.ParentId != "1111111111111111" || extracted.TraceState != "nr=1" {
+		t.Errorf("extractTraceContext() = %+v, want the New Relic span as parent", extracted)
+	}
+}
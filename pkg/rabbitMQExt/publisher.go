@@ -0,0 +1,195 @@
+package rabbitMQExt
+
+import (
+	"boilerplate-service/pkg/newRelicExt"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrUnroutable    = errors.New("rabbitmq message returned as unroutable")
+	ErrNacked        = errors.New("rabbitmq message nacked by broker")
+	ErrChannelClosed = errors.New("rabbitmq channel closed before the message was confirmed")
+)
+
+// Message is an outgoing message. Empty MessageID and ContentType default to a
+// random UUID and "application/json".
+type Message struct {
+	MessageID   string
+	ContentType string
+	Headers     amqp.Table
+	Body        []byte
+	// Transient skips disk persistence, messages are persistent by default
+	Transient bool
+}
+
+// confirmChannel is the part of *amqp.Channel the publisher uses
+type confirmChannel interface {
+	Confirm(noWait bool) error
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
+	Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (confirmation, error)
+	IsClosed() bool
+	Close() error
+}
+
+// confirmation is the broker confirm of one publish
+type confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// amqpChannel publishes mandatory messages on an *amqp.Channel
+type amqpChannel struct {
+	*amqp.Channel
+}
+
+func (c amqpChannel) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (confirmation, error) {
+	return c.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, msg)
+}
+
+// publisher owns a single confirm-mode channel. Publishes are serialized so a
+// basic.return can be matched to the publish that is waiting for its confirm.
+type publisher struct {
+	// open opens a channel, waiting for the connection to come back if it is down
+	open func(ctx context.Context) (confirmChannel, error)
+
+	mu      sync.Mutex
+	ch      confirmChannel
+	returns chan amqp.Return
+}
+
+func newPublisher(r *rabbitMQExt) *publisher {
+	return &publisher{open: func(ctx context.Context) (confirmChannel, error) {
+		ch, err := r.channel(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return amqpChannel{ch}, nil
+	}}
+}
+
+func (p *publisher) channel(ctx context.Context) (confirmChannel, error) {
+	if p.ch != nil && !p.ch.IsClosed() {
+		return p.ch, nil
+	}
+
+	ch, err := p.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	p.ch = ch
+	p.returns = ch.NotifyReturn(make(chan amqp.Return, 16))
+
+	return ch, nil
+}
+
+func (p *publisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch != nil && !p.ch.IsClosed() {
+		p.ch.Close()
+	}
+}
+
+func (r *rabbitMQExt) Publish(ctx context.Context, exchange, routingKey string, msg Message) error {
+	txn := newRelicExt.GetTxnFromCtx(ctx)
+	segment := newrelic.MessageProducerSegment{
+		StartTime:       txn.StartSegmentNow(),
+		Library:         "RabbitMQ",
+		DestinationType: newrelic.MessageExchange,
+		DestinationName: exchange,
+	}
+	defer segment.End()
+
+	if msg.MessageID == "" {
+		msg.MessageID = uuid.New().String()
+	}
+
+	if msg.ContentType == "" {
+		msg.ContentType = "application/json"
+	}
+
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	injectTraceHeaders(ctx, headers)
+
+	if txn != nil {
+		dtHeaders := http.Header{}
+		txn.InsertDistributedTraceHeaders(dtHeaders)
+		injectNewRelicHeaders(dtHeaders, headers)
+	}
+
+	deliveryMode := amqp.Persistent
+	if msg.Transient {
+		deliveryMode = amqp.Transient
+	}
+
+	publishing := amqp.Publishing{
+		MessageId:    msg.MessageID,
+		ContentType:  msg.ContentType,
+		Headers:      headers,
+		Body:         msg.Body,
+		DeliveryMode: deliveryMode,
+		Timestamp:    time.Now(),
+	}
+
+	p := r.publisher
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ch, err := p.channel(ctx)
+	if err != nil {
+		return err
+	}
+
+	confirm, err := ch.Publish(ctx, exchange, routingKey, publishing)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The broker sends basic.return before the ack, so a return for this message is already buffered
+drain:
+	for {
+		select {
+		case ret, ok := <-p.returns:
+			// amqp091 closes the returns before it nacks the pending confirms of a closing channel
+			if !ok {
+				ch.Close()
+				p.ch = nil
+				return ErrChannelClosed
+			}
+			if ret.MessageId == msg.MessageID {
+				return fmt.Errorf("%w: %s (exchange %q, routing key %q)", ErrUnroutable, ret.ReplyText, exchange, routingKey)
+			}
+		default:
+			break drain
+		}
+	}
+
+	if !acked {
+		return ErrNacked
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package rabbitMQExt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeChannel answers each publish with respond, which may send a basic.return or close the channel
+type fakeChannel struct {
+	respond func(ch *fakeChannel, msg amqp.Publishing) confirmation
+
+	mu        sync.Mutex
+	closed    bool
+	returns   chan amqp.Return
+	published []amqp.Publishing
+}
+
+func (c *fakeChannel) Confirm(noWait bool) error {
+	return nil
+}
+
+func (c *fakeChannel) NotifyReturn(returns chan amqp.Return) chan amqp.Return {
+	c.returns = returns
+	return returns
+}
+
+func (c *fakeChannel) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (confirmation, error) {
+	c.mu.Lock()
+	c.published = append(c.published, msg)
+	c.mu.Unlock()
+
+	return c.respond(c, msg), nil
+}
+
+func (c *fakeChannel) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+// Close closes the returns like amqp091 does when the channel shuts down
+func (c *fakeChannel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.returns)
+	}
+	return nil
+}
+
+type fakeConfirmation struct {
+	acked bool
+	// wait blocks WaitContext until the context is done
+	wait bool
+}
+
+func (c fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	if c.wait {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	return c.acked, nil
+}
+
+func newTestPublisher(respond func(ch *fakeChannel, msg amqp.Publishing) confirmation) (*rabbitMQExt, *[]*fakeChannel) {
+	opened := []*fakeChannel{}
+	p := &publisher{open: func(ctx context.Context) (confirmChannel, error) {
+		ch := &fakeChannel{respond: respond}
+		opened = append(opened, ch)
+		return ch, nil
+	}}
+
+	return &rabbitMQExt{publisher: p}, &opened
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(ch *fakeChannel, msg amqp.Publishing) confirmation
+		wantErr error
+	}{
+		{
+			name: "Acked",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				return fakeConfirmation{acked: true}
+			},
+		},
+		{
+			name: "Nacked",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				return fakeConfirmation{acked: false}
+			},
+			wantErr: ErrNacked,
+		},
+		{
+			name: "Returned As Unroutable",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				ch.returns <- amqp.Return{MessageId: msg.MessageId, ReplyText: "NO_ROUTE"}
+				return fakeConfirmation{acked: true}
+			},
+			wantErr: ErrUnroutable,
+		},
+		{
+			name: "Return Of Another Message Is Ignored",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				ch.returns <- amqp.Return{MessageId: "another", ReplyText: "NO_ROUTE"}
+				return fakeConfirmation{acked: true}
+			},
+		},
+		{
+			name: "Channel Closed Before The Confirm",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				ch.Close()
+				return fakeConfirmation{acked: false}
+			},
+			wantErr: ErrChannelClosed,
+		},
+		{
+			name: "Confirm Timed Out",
+			respond: func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+				return fakeConfirmation{wait: true}
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, opened := newTestPublisher(tt.respond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := r.Publish(ctx, "orders", "order.created", Message{Body: []byte(`{"id":1}`)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish() error = %v, want %v", err, tt.wantErr)
+			}
+
+			published := (*opened)[0].published
+			if len(published) != 1 {
+				t.Fatalf("published = %d messages, want 1", len(published))
+			}
+			if msg := published[0]; msg.MessageId == "" || msg.ContentType != "application/json" || msg.DeliveryMode != amqp.Persistent {
+				t.Errorf("published = %+v, want the defaults filled in", msg)
+			}
+		})
+	}
+}
+
+func TestPublishReopensClosedChannel(t *testing.T) {
+	r, opened := newTestPublisher(func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+		return fakeConfirmation{acked: true}
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := r.Publish(context.Background(), "orders", "order.created", Message{}); err != nil {
+			t.Fatalf("Publish() #%d error = %v", i, err)
+		}
+	}
+	if len(*opened) != 1 {
+		t.Fatalf("opened = %d channels, want the channel reused", len(*opened))
+	}
+
+	// A connection drop closes the channel, the next publish opens a new one
+	(*opened)[0].Close()
+	if err := r.Publish(context.Background(), "orders", "order.created", Message{}); err != nil {
+		t.Fatalf("Publish() after the close error = %v", err)
+	}
+	if len(*opened) != 2 || len((*opened)[1].published) != 1 {
+		t.Errorf("opened = %d channels, want the publish on a new channel", len(*opened))
+	}
+}
+
+func TestPublishAfterChannelClosedMidPublish(t *testing.T) {
+	closeFirst := true
+	r, opened := newTestPublisher(func(ch *fakeChannel, msg amqp.Publishing) confirmation {
+		if closeFirst {
+			closeFirst = false
+			ch.Close()
+			return fakeConfirmation{acked: false}
+		}
+		return fakeConfirmation{acked: true}
+	})
+
+	if err := r.Publish(context.Background(), "orders", "order.created", Message{}); !errors.Is(err, ErrChannelClosed) {
+		t.Fatalf("Publish() error = %v, want %v", err, ErrChannelClosed)
+	}
+	if err := r.Publish(context.Background(), "orders", "order.created", Message{}); err != nil {
+		t.Fatalf("Publish() after the close error = %v", err)
+	}
+	if len(*opened) != 2 {
+		t.Errorf("opened = %d channels, want 2", len(*opened))
+	}
+}
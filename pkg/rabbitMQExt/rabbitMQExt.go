@@ -0,0 +1,217 @@
+package rabbitMQExt
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/newRelicExt"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+var ErrClosed = errors.New("rabbitmq connection closed")
+
+type IRabbitMQExt interface {
+	Close() error
+	// Publish sends msg and waits for the broker confirm. With mandatory routing
+	// a message no queue is bound for is returned as ErrUnroutable.
+	Publish(ctx context.Context, exchange, routingKey string, msg Message) error
+	// Consume blocks, handling deliveries from config.Queue until ctx is done,
+	// then stops consuming and waits for in-flight handlers to finish.
+	Consume(ctx context.Context, config ConsumerConfig, handler Handler) error
+}
+
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	VHost    string
+
+	// ConnectionName is shown in the RabbitMQ management UI
+	ConnectionName string
+	// ReconnectDelay is the initial delay between reconnection attempts, doubled up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	Logger   logger.ILogger
+	NewRelic newRelicExt.INewRelicExt
+}
+
+type rabbitMQExt struct {
+	config Config
+	url    string
+
+	mu     sync.RWMutex
+	conn   *amqp.Connection
+	closed bool
+	done   chan struct{}
+
+	publisher *publisher
+}
+
+const (
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
+	connectionPollInterval   = 200 * time.Millisecond
+)
+
+func New(config Config) (IRabbitMQExt, error) {
+	if config.ReconnectDelay == 0 {
+		config.ReconnectDelay = defaultReconnectDelay
+	}
+
+	if config.MaxReconnectDelay == 0 {
+		config.MaxReconnectDelay = defaultMaxReconnectDelay
+	}
+
+	r := &rabbitMQExt{
+		config: config,
+		url: fmt.Sprintf(
+			"amqp://%s:%s@%s:%s/%s",
+			url.QueryEscape(config.Username),
+			url.QueryEscape(config.Password),
+			config.Host,
+			config.Port,
+			url.QueryEscape(config.VHost),
+		),
+		done: make(chan struct{}),
+	}
+	r.publisher = newPublisher(r)
+
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn = conn
+	go r.watch(conn)
+
+	return r, nil
+}
+
+func (r *rabbitMQExt) dial() (*amqp.Connection, error) {
+	properties := amqp.NewConnectionProperties()
+	if r.config.ConnectionName != "" {
+		properties.SetClientConnectionName(r.config.ConnectionName)
+	}
+
+	return amqp.DialConfig(r.url, amqp.Config{
+		Heartbeat:  10 * time.Second,
+		Properties: properties,
+	})
+}
+
+// watch waits for conn to drop and reconnects with exponential backoff until Close is called
+func (r *rabbitMQExt) watch(conn *amqp.Connection) {
+	for {
+		select {
+		case <-r.done:
+			return
+		case amqpErr := <-conn.NotifyClose(make(chan *amqp.Error, 1)):
+			if r.isClosed() {
+				return
+			}
+			r.logWarn("RabbitMQ connection lost, reconnecting", zap.Any("reason", amqpErr))
+		}
+
+		delay := r.config.ReconnectDelay
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-time.After(delay):
+			}
+
+			next, err := r.dial()
+			if err == nil {
+				r.mu.Lock()
+				r.conn = next
+				r.mu.Unlock()
+
+				conn = next
+				r.logInfo("RabbitMQ reconnected")
+				break
+			}
+
+			r.logWarn("RabbitMQ reconnect failed", zap.Error(err), zap.Duration("retry_in", delay))
+			delay *= 2
+			if delay > r.config.MaxReconnectDelay {
+				delay = r.config.MaxReconnectDelay
+			}
+		}
+	}
+}
+
+// channel opens a new channel, waiting for the connection to come back if it is down
+func (r *rabbitMQExt) channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		if r.isClosed() {
+			return nil, ErrClosed
+		}
+
+		r.mu.RLock()
+		conn := r.conn
+		r.mu.RUnlock()
+
+		if conn != nil && !conn.IsClosed() {
+			ch, err := conn.Channel()
+			if err == nil {
+				return ch, nil
+			}
+			if !errors.Is(err, amqp.ErrClosed) {
+				return nil, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-r.done:
+			return nil, ErrClosed
+		case <-time.After(connectionPollInterval):
+		}
+	}
+}
+
+func (r *rabbitMQExt) isClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.closed
+}
+
+func (r *rabbitMQExt) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	conn := r.conn
+	r.mu.Unlock()
+
+	r.publisher.close()
+
+	if conn == nil || conn.IsClosed() {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (r *rabbitMQExt) logInfo(msg string, fields ...zap.Field) {
+	if r.config.Logger != nil {
+		r.config.Logger.Info(context.Background(), msg, fields...)
+	}
+}
+
+func (r *rabbitMQExt) logWarn(msg string, fields ...zap.Field) {
+	if r.config.Logger != nil {
+		r.config.Logger.Warn(context.Background(), msg, fields...)
+	}
+}
@@ -0,0 +1,250 @@
+package redisExt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCacheNotFound is returned by a loader to cache a "not found" result,
+// and returned by the cache while that negative entry is alive.
+var ErrCacheNotFound = errors.New("cache: not found")
+
+type CacheConfig struct {
+	// Prefix is prepended to every key, e.g. "user:"
+	Prefix string
+	// NegativeTTL is how long a not-found result is cached, 0 disables negative caching
+	NegativeTTL time.Duration
+	// Jitter adds up to ttl*Jitter to every expiration so keys written together don't expire together
+	Jitter float64
+	// LockExpiry bounds how long a pod may hold the cross-pod load lock
+	LockExpiry time.Duration
+	// TagTTL is the minimum lifetime of a tag set, it should outlive the keys it tracks
+	TagTTL time.Duration
+	// LoadTimeout bounds a shared load, which outlives the caller that started it
+	LoadTimeout time.Duration
+}
+
+// Cache is a typed cache-aside helper. Concurrent misses for one key are collapsed
+// in-process with singleflight and across pods with a redsync mutex, so the loader
+// runs once per expiry.
+type Cache[T any] struct {
+	redis  IRedisExt
+	group  singleflight.Group
+	config CacheConfig
+}
+
+type cacheEntry[T any] struct {
+	Value    T    `json:"v"`
+	NotFound bool `json:"nf,omitempty"`
+}
+
+const (
+	defaultCacheLockExpiry  = 10 * time.Second
+	defaultCacheTagTTL      = 24 * time.Hour
+	defaultCacheLoadTimeout = 10 * time.Second
+)
+
+func NewCache[T any](redis IRedisExt, config CacheConfig) *Cache[T] {
+	if config.LockExpiry == 0 {
+		config.LockExpiry = defaultCacheLockExpiry
+	}
+
+	if config.TagTTL == 0 {
+		config.TagTTL = defaultCacheTagTTL
+	}
+
+	if config.LoadTimeout == 0 {
+		config.LoadTimeout = defaultCacheLoadTimeout
+	}
+
+	return &Cache[T]{redis: redis, config: config}
+}
+
+// Get returns the cached value, false when the key is missing,
+// or ErrCacheNotFound when a negative entry is cached.
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var zero T
+
+	raw, err := c.redis.Get(ctx, c.config.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+
+	var entry cacheEntry[T]
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return zero, false, err
+	}
+
+	if entry.NotFound {
+		return zero, true, ErrCacheNotFound
+	}
+
+	return entry.Value, true, nil
+}
+
+// Set stores value under key and registers it under every tag
+func (c *Cache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration, tags ...string) error {
+	return c.set(ctx, key, cacheEntry[T]{Value: value}, ttl, tags)
+}
+
+// GetOrLoad returns the cached value for key, or calls loader, caches and returns its result.
+// When loader returns ErrCacheNotFound the miss is cached for NegativeTTL.
+//
+// The load is shared by every concurrent caller, so it runs detached from ctx for at most
+// LoadTimeout; a caller whose ctx ends stops waiting without failing the others.
+func (c *Cache[T]) GetOrLoad(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	tags ...string,
+) (T, error) {
+	if value, ok, err := c.Get(ctx, key); ok || err != nil {
+		return value, err
+	}
+
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.config.LoadTimeout)
+		defer cancel()
+
+		return c.load(loadCtx, key, ttl, loader, tags)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+
+		value, _ := result.Val.(T)
+		return value, nil
+	}
+}
+
+func (c *Cache[T]) load(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	tags []string,
+) (T, error) {
+	mutex := c.redis.NewMutex(
+		"lock:"+c.config.Prefix+key,
+		redsync.WithExpiry(c.config.LockExpiry),
+	)
+
+	// If the lock can't be taken (e.g. redis hiccup) load anyway rather than failing the caller
+	if err := mutex.LockContext(ctx); err == nil {
+		defer mutex.UnlockContext(context.WithoutCancel(ctx))
+
+		// Another pod may have filled the key while we were waiting for the lock
+		if value, ok, err := c.Get(ctx, key); ok || err != nil {
+			return value, err
+		}
+	}
+
+	value, err := loader(ctx)
+	if errors.Is(err, ErrCacheNotFound) {
+		if c.config.NegativeTTL > 0 {
+			c.set(ctx, key, cacheEntry[T]{NotFound: true}, c.config.NegativeTTL, tags)
+		}
+		return value, err
+	}
+	if err != nil {
+		return value, err
+	}
+
+	return value, c.set(ctx, key, cacheEntry[T]{Value: value}, ttl, tags)
+}
+
+func (c *Cache[T]) set(ctx context.Context, key string, entry cacheEntry[T], ttl time.Duration, tags []string) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	ttl = c.jitter(ttl)
+	if err := c.redis.Set(ctx, c.config.Prefix+key, raw, ttl).Err(); err != nil {
+		return err
+	}
+
+	tagTTL := c.config.TagTTL
+	if ttl > tagTTL {
+		tagTTL = ttl
+	}
+
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		if err := c.redis.SAdd(ctx, tagKey, c.config.Prefix+key).Err(); err != nil {
+			return err
+		}
+		if err := c.redis.Expire(ctx, tagKey, tagTTL).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes keys from the cache
+func (c *Cache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.config.Prefix + key
+	}
+
+	return c.del(ctx, prefixed)
+}
+
+// InvalidateTag removes every key stored with tag, e.g. all keys derived from one entity
+func (c *Cache[T]) InvalidateTag(ctx context.Context, tag string) error {
+	tagKey := c.tagKey(tag)
+
+	keys, err := c.redis.SMembers(ctx, tagKey).Result()
+	if err != nil {
+		return err
+	}
+
+	return c.del(ctx, append(keys, tagKey))
+}
+
+// del removes keys one DEL each in a single pipeline, as the keys may live in different
+// cluster slots and a multi-key DEL would fail with CROSSSLOT
+func (c *Cache[T]) del(ctx context.Context, keys []string) error {
+	_, err := c.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (c *Cache[T]) tagKey(tag string) string {
+	return "tag:" + c.config.Prefix + tag
+}
+
+func (c *Cache[T]) jitter(ttl time.Duration) time.Duration {
+	if c.config.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+
+	return ttl + time.Duration(rand.Int63n(int64(float64(ttl)*c.config.Jitter)+1))
+}
@@ -0,0 +1,260 @@
+package redisExt_test
+
+import (
+	"boilerplate-service/pkg/redisExt"
+	"boilerplate-service/pkg/redisExt/redisTest"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	errLoad := errors.New("database down")
+
+	tests := []struct {
+		name   string
+		config redisExt.CacheConfig
+		// results are returned by the loader in turn, the loader is called once per GetOrLoad miss
+		results        []error
+		wantErrs       []error
+		wantLoads      int
+		wantExpiration time.Duration
+	}{
+		{
+			name:           "Loaded Value Is Cached",
+			config:         redisExt.CacheConfig{Prefix: "user:"},
+			results:        []error{nil},
+			wantErrs:       []error{nil, nil},
+			wantLoads:      1,
+			wantExpiration: time.Minute,
+		},
+		{
+			name:           "Not Found Is Cached For NegativeTTL",
+			config:         redisExt.CacheConfig{Prefix: "user:", NegativeTTL: 5 * time.Second},
+			results:        []error{redisExt.ErrCacheNotFound},
+			wantErrs:       []error{redisExt.ErrCacheNotFound, redisExt.ErrCacheNotFound},
+			wantLoads:      1,
+			wantExpiration: 5 * time.Second,
+		},
+		{
+			name:      "Not Found Without NegativeTTL Loads Again",
+			config:    redisExt.CacheConfig{Prefix: "user:"},
+			results:   []error{redisExt.ErrCacheNotFound, redisExt.ErrCacheNotFound},
+			wantErrs:  []error{redisExt.ErrCacheNotFound, redisExt.ErrCacheNotFound},
+			wantLoads: 2,
+		},
+		{
+			name:           "Loader Errors Are Not Cached",
+			config:         redisExt.CacheConfig{Prefix: "user:"},
+			results:        []error{errLoad, nil},
+			wantErrs:       []error{errLoad, nil},
+			wantLoads:      2,
+			wantExpiration: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := redisTest.New()
+			cache := redisExt.NewCache[user](redis, tt.config)
+
+			loads := 0
+			loader := func(ctx context.Context) (user, error) {
+				err := tt.results[loads]
+				loads++
+				return user{Name: "budi"}, err
+			}
+
+			for i, wantErr := range tt.wantErrs {
+				got, err := cache.GetOrLoad(context.Background(), "1", time.Minute, loader)
+				if !errors.Is(err, wantErr) {
+					t.Fatalf("GetOrLoad() #%d error = %v, want %v", i, err, wantErr)
+				}
+				if err == nil && got.Name != "budi" {
+					t.Errorf("GetOrLoad() #%d = %+v", i, got)
+				}
+			}
+
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+			if expiration := redis.Expiration("user:1"); expiration != tt.wantExpiration {
+				t.Errorf("expiration = %v, want %v", expiration, tt.wantExpiration)
+			}
+			if _, locked := redis.Value("lock:user:1"); locked {
+				t.Error("the load lock wasn't released")
+			}
+		})
+	}
+}
+
+func TestCacheGetOrLoadCollapsesConcurrentMisses(t *testing.T) {
+	cache := redisExt.NewCache[user](redisTest.New(), redisExt.CacheConfig{})
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (user, error) {
+		loads.Add(1)
+		<-release
+		return user{Name: "budi"}, nil
+	}
+
+	// Callers either join the running load or find its result in the cache
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := cache.GetOrLoad(context.Background(), "1", time.Minute, loader); err != nil || got.Name != "budi" {
+				t.Errorf("GetOrLoad() = %+v, %v", got, err)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("loads = %d, want 1", loads.Load())
+	}
+}
+
+func TestCacheGetOrLoadWaitsForAnotherPod(t *testing.T) {
+	redis := redisTest.New()
+	cache := redisExt.NewCache[user](redis, redisExt.CacheConfig{})
+
+	// Another pod holds the load lock, and fills the key before releasing it
+	redis.Set(context.Background(), "lock:1", "other-pod", time.Second)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		redisExt.NewCache[user](redis, redisExt.CacheConfig{}).Set(context.Background(), "1", user{Name: "from other pod"}, time.Minute)
+		redis.Del(context.Background(), "lock:1")
+	}()
+
+	got, err := cache.GetOrLoad(context.Background(), "1", time.Minute, func(ctx context.Context) (user, error) {
+		t.Error("loader called while another pod was loading")
+		return user{}, nil
+	})
+	if err != nil || got.Name != "from other pod" {
+		t.Errorf("GetOrLoad() = %+v, %v, want the other pod's value", got, err)
+	}
+}
+
+func TestCacheGetOrLoadCallerGivesUp(t *testing.T) {
+	redis := redisTest.New()
+	cache := redisExt.NewCache[user](redis, redisExt.CacheConfig{})
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (user, error) {
+		<-release
+		return user{Name: "budi"}, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	if _, err := cache.GetOrLoad(ctx, "1", time.Minute, loader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrLoad() error = %v, want context.Canceled", err)
+	}
+
+	// The load isn't cancelled with the caller, its result is still cached for the others
+	close(release)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if _, ok := redis.Value("1"); ok {
+			return
+		}
+	}
+	t.Error("the detached load wasn't cached")
+}
+
+func TestCacheJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		jitter  float64
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{name: "Without Jitter", jitter: 0, wantMin: time.Minute, wantMax: time.Minute},
+		{name: "Half", jitter: 0.5, wantMin: time.Minute, wantMax: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := redisTest.New()
+			cache := redisExt.NewCache[user](redis, redisExt.CacheConfig{Jitter: tt.jitter})
+
+			for i := 0; i < 50; i++ {
+				if err := cache.Set(context.Background(), "1", user{}, time.Minute); err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+				if expiration := redis.Expiration("1"); expiration < tt.wantMin || expiration > tt.wantMax {
+					t.Fatalf("expiration = %v, want between %v and %v", expiration, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheInvalidateTag(t *testing.T) {
+	redis := redisTest.New()
+	cache := redisExt.NewCache[user](redis, redisExt.CacheConfig{Prefix: "user:", TagTTL: time.Hour})
+	ctx := context.Background()
+
+	cache.Set(ctx, "1", user{Name: "budi"}, time.Minute, "team:a")
+	cache.Set(ctx, "1:profile", user{Name: "budi"}, 2*time.Hour, "team:a")
+	cache.Set(ctx, "2", user{Name: "ani"}, time.Minute, "team:b")
+
+	members := redis.Members("tag:user:team:a")
+	sort.Strings(members)
+	if len(members) != 2 || members[0] != "user:1" || members[1] != "user:1:profile" {
+		t.Fatalf("tag members = %v", members)
+	}
+	// The tag set outlives the longest lived key it tracks
+	if expiration := redis.Expiration("tag:user:team:a"); expiration != 2*time.Hour {
+		t.Errorf("tag expiration = %v, want 2h", expiration)
+	}
+
+	if err := cache.InvalidateTag(ctx, "team:a"); err != nil {
+		t.Fatalf("InvalidateTag() error = %v", err)
+	}
+
+	for key, want := range map[string]bool{"user:1": false, "user:1:profile": false, "user:2": true} {
+		if _, ok := redis.Value(key); ok != want {
+			t.Errorf("%s present = %v, want %v", key, ok, want)
+		}
+	}
+	if members := redis.Members("tag:user:team:a"); len(members) != 0 {
+		t.Errorf("tag members = %v, want the tag removed", members)
+	}
+}
+
+func TestCacheRedisError(t *testing.T) {
+	redis := redisTest.New()
+	cache := redisExt.NewCache[user](redis, redisExt.CacheConfig{})
+	errRedis := errors.New("connection refused")
+	redis.SetErr(errRedis)
+
+	_, err := cache.GetOrLoad(context.Background(), "1", time.Minute, func(ctx context.Context) (user, error) {
+		t.Error("loader called although redis failed")
+		return user{}, nil
+	})
+	if !errors.Is(err, errRedis) {
+		t.Errorf("GetOrLoad() error = %v, want %v", err, errRedis)
+	}
+
+	if err := cache.Delete(context.Background(), "1"); !errors.Is(err, errRedis) {
+		t.Errorf("Delete() error = %v, want %v", err, errRedis)
+	}
+}
@@ -3,7 +3,8 @@ package redisExt
 import (
 	"boilerplate-service/pkg/newRelicExt"
 	"context"
-
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"time"
 
@@ -22,35 +23,200 @@ type IRedisExt interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
 	Ping(ctx context.Context) *redis.StatusCmd
 
+	// Keys
+	Exists(ctx context.Context, keys ...string) *redis.IntCmd
+	TTL(ctx context.Context, key string) *redis.DurationCmd
+	PExpire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Persist(ctx context.Context, key string) *redis.BoolCmd
+
+	// Strings
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	Incr(ctx context.Context, key string) *redis.IntCmd
+	IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd
+	Decr(ctx context.Context, key string) *redis.IntCmd
+	DecrBy(ctx context.Context, key string, decrement int64) *redis.IntCmd
+	GetDel(ctx context.Context, key string) *redis.StringCmd
+
+	// Hashes
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+	HExists(ctx context.Context, key, field string) *redis.BoolCmd
+	HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd
+	HLen(ctx context.Context, key string) *redis.IntCmd
+
+	// Lists
+	LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LPop(ctx context.Context, key string) *redis.StringCmd
+	RPop(ctx context.Context, key string) *redis.StringCmd
+	LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	LLen(ctx context.Context, key string) *redis.IntCmd
+	LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd
+	LRem(ctx context.Context, key string, count int64, value interface{}) *redis.IntCmd
+
+	// Sets
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd
+	SCard(ctx context.Context, key string) *redis.IntCmd
+
+	// Sorted sets
+	ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	ZIncrBy(ctx context.Context, key string, increment float64, member string) *redis.FloatCmd
+	ZScore(ctx context.Context, key, member string) *redis.FloatCmd
+	ZCard(ctx context.Context, key string) *redis.IntCmd
+	ZCount(ctx context.Context, key, min, max string) *redis.IntCmd
+	ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	ZRangeWithScores(ctx context.Context, key string, start, stop int64) *redis.ZSliceCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	ZRevRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	ZRemRangeByScore(ctx context.Context, key, min, max string) *redis.IntCmd
+
+	// Pipelines
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+	TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+
+	// Lua scripts
+	RegisterScript(ctx context.Context, name, src string) error
+	RunScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd
+
 	// Redsync
 	NewMutex(name string, options ...redsync.Option) *redsync.Mutex
 }
 
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+type Config struct {
+	// Mode is one of ModeStandalone (default), ModeSentinel or ModeCluster
+	Mode string
+
+	// Host and Port are used in standalone mode
+	Host string
+	Port string
+	// Addrs are the sentinel addresses in sentinel mode, or the seed nodes in cluster mode
+	Addrs []string
+	// MasterName is the sentinel master set name
+	MasterName string
+	// DB is ignored in cluster mode
+	DB int
+
+	Username         string
+	Password         string
+	SentinelUsername string
+	SentinelPassword string
+
+	TLSEnabled            bool
+	TLSInsecureSkipVerify bool
+
+	PoolSize     int
+	MinIdleConns int
+	// Timeouts in seconds, 0 keeps the go-redis defaults
+	DialTimeout  int
+	ReadTimeout  int
+	WriteTimeout int
+}
+
 type redisExt struct {
-	client *redis.Client
-	rs     *redsync.Redsync
+	client  redis.UniversalClient
+	rs      *redsync.Redsync
+	scripts *scriptRegistry
 }
 
-func New(host, port, password string, db int) (IRedisExt, error) {
-	opts := &redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", host, port),
-		Password: password,
-		DB:       db,
+func New(config Config) (IRedisExt, error) {
+	var tlsConfig *tls.Config
+	if config.TLSEnabled {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: config.TLSInsecureSkipVerify,
+		}
 	}
-	client := redis.NewClient(opts)
 
-	client.AddHook(nrredis.NewHook(opts))
+	var client redis.UniversalClient
+	switch config.Mode {
+	case "", ModeStandalone:
+		opts := &redis.Options{
+			Addr:         fmt.Sprintf("%s:%s", config.Host, config.Port),
+			Username:     config.Username,
+			Password:     config.Password,
+			DB:           config.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			DialTimeout:  seconds(config.DialTimeout),
+			ReadTimeout:  seconds(config.ReadTimeout),
+			WriteTimeout: seconds(config.WriteTimeout),
+		}
+		standalone := redis.NewClient(opts)
+		standalone.AddHook(nrredis.NewHook(opts))
+		client = standalone
+	case ModeSentinel:
+		if config.MasterName == "" || len(config.Addrs) == 0 {
+			return nil, errors.New("redis sentinel mode requires master name and sentinel addrs")
+		}
+		failover := redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.Addrs,
+			SentinelUsername: config.SentinelUsername,
+			SentinelPassword: config.SentinelPassword,
+			Username:         config.Username,
+			Password:         config.Password,
+			DB:               config.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         config.PoolSize,
+			MinIdleConns:     config.MinIdleConns,
+			DialTimeout:      seconds(config.DialTimeout),
+			ReadTimeout:      seconds(config.ReadTimeout),
+			WriteTimeout:     seconds(config.WriteTimeout),
+		})
+		// The master address changes on failover, so the segment is reported without an instance
+		failover.AddHook(nrredis.NewHook(nil))
+		client = failover
+	case ModeCluster:
+		if len(config.Addrs) == 0 {
+			return nil, errors.New("redis cluster mode requires addrs")
+		}
+		cluster := redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.Addrs,
+			Username:     config.Username,
+			Password:     config.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			DialTimeout:  seconds(config.DialTimeout),
+			ReadTimeout:  seconds(config.ReadTimeout),
+			WriteTimeout: seconds(config.WriteTimeout),
+		})
+		cluster.AddHook(nrredis.NewHook(nil))
+		client = cluster
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", config.Mode)
+	}
 
 	err := client.Ping(context.Background()).Err()
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
 	rs := redsync.New(goredis.NewPool(client))
 
-	return &redisExt{client, rs}, nil
+	return &redisExt{client, rs, newScriptRegistry()}, nil
+}
+
+func seconds(value int) time.Duration {
+	return time.Duration(value) * time.Second
 }
 
 func (r *redisExt) Close() error {
@@ -77,6 +243,232 @@ func (r *redisExt) SetNX(ctx context.Context, key string, value interface{}, exp
 	return r.client.SetNX(ctx, key, value, expiration)
 }
 
+func (r *redisExt) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Expire(ctx, key, expiration)
+}
+
+func (r *redisExt) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.SAdd(ctx, key, members...)
+}
+
+func (r *redisExt) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.SMembers(ctx, key)
+}
+
+func (r *redisExt) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Exists(ctx, keys...)
+}
+
+func (r *redisExt) TTL(ctx context.Context, key string) *redis.DurationCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.TTL(ctx, key)
+}
+
+func (r *redisExt) PExpire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.PExpire(ctx, key, expiration)
+}
+
+func (r *redisExt) Persist(ctx context.Context, key string) *redis.BoolCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Persist(ctx, key)
+}
+
+func (r *redisExt) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.MGet(ctx, keys...)
+}
+
+func (r *redisExt) Incr(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Incr(ctx, key)
+}
+
+func (r *redisExt) IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.IncrBy(ctx, key, value)
+}
+
+func (r *redisExt) Decr(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Decr(ctx, key)
+}
+
+func (r *redisExt) DecrBy(ctx context.Context, key string, decrement int64) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.DecrBy(ctx, key, decrement)
+}
+
+func (r *redisExt) GetDel(ctx context.Context, key string) *redis.StringCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.GetDel(ctx, key)
+}
+
+func (r *redisExt) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HSet(ctx, key, values...)
+}
+
+func (r *redisExt) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HGet(ctx, key, field)
+}
+
+func (r *redisExt) HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HGetAll(ctx, key)
+}
+
+func (r *redisExt) HMGet(ctx context.Context, key string, fields ...string) *redis.SliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HMGet(ctx, key, fields...)
+}
+
+func (r *redisExt) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HDel(ctx, key, fields...)
+}
+
+func (r *redisExt) HExists(ctx context.Context, key, field string) *redis.BoolCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HExists(ctx, key, field)
+}
+
+func (r *redisExt) HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HIncrBy(ctx, key, field, incr)
+}
+
+func (r *redisExt) HLen(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.HLen(ctx, key)
+}
+
+func (r *redisExt) LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LPush(ctx, key, values...)
+}
+
+func (r *redisExt) RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.RPush(ctx, key, values...)
+}
+
+func (r *redisExt) LPop(ctx context.Context, key string) *redis.StringCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LPop(ctx, key)
+}
+
+func (r *redisExt) RPop(ctx context.Context, key string) *redis.StringCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.RPop(ctx, key)
+}
+
+func (r *redisExt) LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LRange(ctx, key, start, stop)
+}
+
+func (r *redisExt) LLen(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LLen(ctx, key)
+}
+
+func (r *redisExt) LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LTrim(ctx, key, start, stop)
+}
+
+func (r *redisExt) LRem(ctx context.Context, key string, count int64, value interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.LRem(ctx, key, count, value)
+}
+
+func (r *redisExt) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.SRem(ctx, key, members...)
+}
+
+func (r *redisExt) SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.SIsMember(ctx, key, member)
+}
+
+func (r *redisExt) SCard(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.SCard(ctx, key)
+}
+
+func (r *redisExt) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZAdd(ctx, key, members...)
+}
+
+func (r *redisExt) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRem(ctx, key, members...)
+}
+
+func (r *redisExt) ZIncrBy(ctx context.Context, key string, increment float64, member string) *redis.FloatCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZIncrBy(ctx, key, increment, member)
+}
+
+func (r *redisExt) ZScore(ctx context.Context, key, member string) *redis.FloatCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZScore(ctx, key, member)
+}
+
+func (r *redisExt) ZCard(ctx context.Context, key string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZCard(ctx, key)
+}
+
+func (r *redisExt) ZCount(ctx context.Context, key, min, max string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZCount(ctx, key, min, max)
+}
+
+func (r *redisExt) ZRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRange(ctx, key, start, stop)
+}
+
+func (r *redisExt) ZRangeWithScores(ctx context.Context, key string, start, stop int64) *redis.ZSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRangeWithScores(ctx, key, start, stop)
+}
+
+func (r *redisExt) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRangeByScore(ctx, key, opt)
+}
+
+func (r *redisExt) ZRevRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRevRange(ctx, key, start, stop)
+}
+
+func (r *redisExt) ZRemRangeByScore(ctx context.Context, key, min, max string) *redis.IntCmd {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.ZRemRangeByScore(ctx, key, min, max)
+}
+
+func (r *redisExt) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.Pipelined(ctx, fn)
+}
+
+// TxPipelined wraps the queued commands in MULTI/EXEC
+func (r *redisExt) TxPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return r.client.TxPipelined(ctx, fn)
+}
+
 func (r *redisExt) NewMutex(name string, options ...redsync.Option) *redsync.Mutex {
 	return r.rs.NewMutex(name, options...)
 }
@@ -0,0 +1,338 @@
+// Package redisTest provides an in-memory redisExt.IRedisExt for tests. It implements the
+// string, set and expiry commands, pipelines of DEL and redsync mutexes; calling any other
+// command panics.
+package redisTest
+
+import (
+	"boilerplate-service/pkg/redisExt"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	redsyncredis "github.com/go-redsync/redsync/v4/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+var errUnsupported = errors.New("redisTest: command not supported in a pipeline")
+
+// Redis keeps values in memory. Keys never expire by themselves, the expiration last given to
+// a key is kept for assertions.
+type Redis struct {
+	redisExt.IRedisExt
+
+	mu          sync.Mutex
+	values      map[string]string
+	sets        map[string]map[string]bool
+	expirations map[string]time.Duration
+	calls       map[string]int
+	err         error
+}
+
+func New() *Redis {
+	return &Redis{
+		values:      map[string]string{},
+		sets:        map[string]map[string]bool{},
+		expirations: map[string]time.Duration{},
+		calls:       map[string]int{},
+	}
+}
+
+// SetErr makes every following command fail with err, nil restores them
+func (r *Redis) SetErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.err = err
+}
+
+// Value returns the string stored under key
+func (r *Redis) Value(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.values[key]
+	return value, ok
+}
+
+// Members returns the members of the set stored under key
+func (r *Redis) Members(key string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []string{}
+	for member := range r.sets[key] {
+		members = append(members, member)
+	}
+	return members
+}
+
+// Expiration returns the expiration last set on key, 0 when it has none
+func (r *Redis) Expiration(key string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.expirations[key]
+}
+
+// Calls returns how many times command was called, e.g. "set"
+func (r *Redis) Calls(command string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls[command]
+}
+
+// begin locks r and counts command, the returned error fails the command
+func (r *Redis) begin(command string) error {
+	r.mu.Lock()
+	r.calls[command]++
+	return r.err
+}
+
+func (r *Redis) Get(ctx context.Context, key string) *redis.StringCmd {
+	err := r.begin("get")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewStringResult("", err)
+	}
+
+	value, ok := r.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	err := r.begin("set")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewStatusResult("", err)
+	}
+
+	r.values[key] = toString(value)
+	r.expirations[key] = expiration
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	err := r.begin("setnx")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	if _, ok := r.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	r.values[key] = toString(value)
+	r.expirations[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *Redis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	err := r.begin("del")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewIntResult(0, err)
+	}
+	return redis.NewIntResult(r.del(keys), nil)
+}
+
+func (r *Redis) del(keys []string) int64 {
+	deleted := int64(0)
+	for _, key := range keys {
+		_, isValue := r.values[key]
+		_, isSet := r.sets[key]
+		if isValue || isSet {
+			deleted++
+		}
+
+		delete(r.values, key)
+		delete(r.sets, key)
+		delete(r.expirations, key)
+	}
+	return deleted
+}
+
+func (r *Redis) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	err := r.begin("expire")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	_, isValue := r.values[key]
+	_, isSet := r.sets[key]
+	if !isValue && !isSet {
+		return redis.NewBoolResult(false, nil)
+	}
+	r.expirations[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *Redis) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	err := r.begin("sadd")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	if r.sets[key] == nil {
+		r.sets[key] = map[string]bool{}
+	}
+
+	added := int64(0)
+	for _, member := range members {
+		if !r.sets[key][toString(member)] {
+			r.sets[key][toString(member)] = true
+			added++
+		}
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+func (r *Redis) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	err := r.begin("smembers")
+	defer r.mu.Unlock()
+
+	if err != nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+
+	members := []string{}
+	for member := range r.sets[key] {
+		members = append(members, member)
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+// Pipelined runs fn against a client whose pipelines are answered by r, only DEL is supported
+func (r *Redis) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	client := redis.NewClient(&redis.Options{Addr: "redisTest:0"})
+	defer client.Close()
+
+	client.AddHook(pipelineHook{r})
+	return client.Pipelined(ctx, fn)
+}
+
+func (r *Redis) NewMutex(name string, options ...redsync.Option) *redsync.Mutex {
+	return redsync.New(redsyncPool{r}).NewMutex(name, options...)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// pipelineHook answers pipelined commands from memory, the client never dials
+type pipelineHook struct {
+	redis *Redis
+}
+
+func (h pipelineHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errUnsupported
+	}
+}
+
+func (h pipelineHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		cmd.SetErr(errUnsupported)
+		return errUnsupported
+	}
+}
+
+func (h pipelineHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if !strings.EqualFold(cmd.Name(), "del") {
+				cmd.SetErr(errUnsupported)
+				return errUnsupported
+			}
+
+			keys := []string{}
+			for _, arg := range cmd.Args()[1:] {
+				keys = append(keys, toString(arg))
+			}
+
+			result := h.redis.Del(ctx, keys...)
+			if err := result.Err(); err != nil {
+				cmd.SetErr(err)
+				return err
+			}
+			cmd.(*redis.IntCmd).SetVal(result.Val())
+		}
+		return nil
+	}
+}
+
+type redsyncPool struct {
+	redis *Redis
+}
+
+func (p redsyncPool) Get(ctx context.Context) (redsyncredis.Conn, error) {
+	return redsyncConn{p.redis}, nil
+}
+
+// redsyncConn is enough of a connection for a mutex to lock, extend and unlock
+type redsyncConn struct {
+	redis *Redis
+}
+
+func (c redsyncConn) Get(name string) (string, error) {
+	return c.redis.Get(context.Background(), name).Result()
+}
+
+func (c redsyncConn) Set(name string, value string) (bool, error) {
+	return true, c.redis.Set(context.Background(), name, value, 0).Err()
+}
+
+func (c redsyncConn) SetNX(name string, value string, expiry time.Duration) (bool, error) {
+	return c.redis.SetNX(context.Background(), name, value, expiry).Result()
+}
+
+// Eval runs the unlock and extend scripts, both only act when the caller still owns the lock
+func (c redsyncConn) Eval(script *redsyncredis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	err := c.redis.begin("eval")
+	defer c.redis.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	name, value := keysAndArgs[0].(string), keysAndArgs[1].(string)
+	if c.redis.values[name] != value {
+		return int64(0), nil
+	}
+
+	if len(keysAndArgs) == 2 {
+		c.redis.del([]string{name})
+	}
+	return int64(1), nil
+}
+
+func (c redsyncConn) PTTL(name string) (time.Duration, error) {
+	return time.Minute, nil
+}
+
+func (c redsyncConn) Close() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package redisExt
+
+import (
+	"boilerplate-service/pkg/newRelicExt"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/redis/go-redis/v9"
+)
+
+// scriptRegistry keeps Lua scripts by name so callers only ship the SHA on each run
+type scriptRegistry struct {
+	mu      sync.RWMutex
+	scripts map[string]*redis.Script
+}
+
+func newScriptRegistry() *scriptRegistry {
+	return &scriptRegistry{scripts: map[string]*redis.Script{}}
+}
+
+// RegisterScript loads src into the script cache under name. Registering the same
+// name and source again is a no-op, so it is safe to call from several constructors.
+func (r *redisExt) RegisterScript(ctx context.Context, name, src string) error {
+	script := redis.NewScript(src)
+
+	r.scripts.mu.Lock()
+	existing, ok := r.scripts.scripts[name]
+	if ok && existing.Hash() != script.Hash() {
+		r.scripts.mu.Unlock()
+		return fmt.Errorf("redis script %q already registered with a different source", name)
+	}
+	r.scripts.scripts[name] = script
+	r.scripts.mu.Unlock()
+
+	if ok {
+		return nil
+	}
+
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return script.Load(ctx, r.client).Err()
+}
+
+// RunScript runs a registered script with EVALSHA, falling back to EVAL when
+// the server lost its script cache (restart, failover, SCRIPT FLUSH).
+func (r *redisExt) RunScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	r.scripts.mu.RLock()
+	script, ok := r.scripts.scripts[name]
+	r.scripts.mu.RUnlock()
+
+	if !ok {
+		cmd := redis.NewCmd(ctx)
+		cmd.SetErr(fmt.Errorf("redis script %q is not registered", name))
+		return cmd
+	}
+
+	ctx = newrelic.NewContext(ctx, newRelicExt.GetTxnFromCtx(ctx))
+	return script.Run(ctx, r.client, keys, args...)
+}
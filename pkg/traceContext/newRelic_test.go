@@ -0,0 +1,92 @@
+package traceContext
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func TestWithTraceMetadata(t *testing.T) {
+	const (
+		nrTraceId = "5c7a54c2c0aaa23b0af847af3237beb8"
+		nrSpanId  = "b7ad6b7169203331"
+	)
+	metadata := newrelic.TraceMetadata{TraceID: nrTraceId, SpanID: nrSpanId}
+
+	continued, err := Parse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	continued.RequestId = "req-1"
+
+	continuedByNewRelic, err := Parse("00-" + nrTraceId + "-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		trace         TraceContext
+		metadata      newrelic.TraceMetadata
+		wantTraceId   string
+		wantNRSpan    bool
+		wantRequestId string
+	}{
+		{
+			name:          "Generated Request Id Follows The New Relic Trace",
+			trace:         New(),
+			metadata:      metadata,
+			wantTraceId:   nrTraceId,
+			wantNRSpan:    true,
+			wantRequestId: "5c7a54c2-c0aa-a23b-0af8-47af3237beb8",
+		},
+		{
+			name:          "Caller Request Id Is Kept",
+			trace:         FromRequestId("req-1"),
+			metadata:      metadata,
+			wantTraceId:   nrTraceId,
+			wantNRSpan:    true,
+			wantRequestId: "req-1",
+		},
+		{
+			name:          "Continued Trace Keeps Its Ids",
+			trace:         continued,
+			metadata:      metadata,
+			wantTraceId:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantRequestId: "req-1",
+		},
+		{
+			name:        "Continued Trace Shared With New Relic Takes Its Span",
+			trace:       continuedByNewRelic,
+			metadata:    metadata,
+			wantTraceId: nrTraceId,
+			wantNRSpan:  true,
+		},
+		{
+			name:          "Metadata Without Span Is Ignored",
+			trace:         continued,
+			metadata:      newrelic.TraceMetadata{TraceID: nrTraceId},
+			wantTraceId:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantRequestId: "req-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.trace.withTraceMetadata(tt.metadata)
+
+			if got.TraceId != tt.wantTraceId {
+				t.Errorf("TraceId = %q, want %q", got.TraceId, tt.wantTraceId)
+			}
+			if (got.SpanId == nrSpanId) != tt.wantNRSpan {
+				t.Errorf("SpanId = %q, want the New Relic span %v", got.SpanId, tt.wantNRSpan)
+			}
+			if got.RequestId != tt.wantRequestId {
+				t.Errorf("RequestId = %q, want %q", got.RequestId, tt.wantRequestId)
+			}
+			if got.ParentId != tt.trace.ParentId {
+				t.Errorf("ParentId = %q, want %q", got.ParentId, tt.trace.ParentId)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package traceContext
+
+import (
+	"boilerplate-service/constant"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Headers carrying the trace context between services, over HTTP and message headers alike
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+	HeaderRequestId   = "X-Request-Id"
+)
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+	// maxTracestateLength is the limit W3C lets vendors truncate tracestate at
+	maxTracestateLength = 512
+	// maxRequestIdLength leaves room for the usual uuid and vendor request id formats
+	maxRequestIdLength = 128
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext is the W3C trace context of the current request or message, together with its
+// X-Request-Id. It is stored in the context with NewContext.
+type TraceContext struct {
+	// TraceId is 32 lowercase hex characters, shared by every service on the trace
+	TraceId string
+	// SpanId is 16 lowercase hex characters, the parent id of our outgoing calls
+	SpanId string
+	// ParentId is the span id of the caller, "" when the trace started here
+	ParentId   string
+	Flags      byte
+	TraceState string
+	// RequestId is the X-Request-Id, the uuid form of TraceId when the caller didn't send one
+	RequestId string
+
+	// generatedRequestId is set when RequestId was made up here, it then follows TraceId
+	generatedRequestId bool
+}
+
+// New starts a trace
+func New() TraceContext {
+	trace := FromRequestId(uuid.New().String())
+	trace.generatedRequestId = true
+	return trace
+}
+
+// FromRequestId starts a trace for a caller that only sent a request id. A uuid request id is
+// reused as the trace id, so both can be searched with the same value.
+func FromRequestId(requestId string) TraceContext {
+	traceId := ""
+	if id, err := uuid.Parse(requestId); err == nil {
+		traceId = hex.EncodeToString(id[:])
+	}
+	if !isValidId(traceId, 32) {
+		traceId = randomHex(16)
+	}
+
+	return TraceContext{
+		TraceId:   traceId,
+		SpanId:    randomHex(8),
+		Flags:     flagSampled,
+		RequestId: requestId,
+	}
+}
+
+// Parse reads a W3C traceparent header, the returned context continues the trace in a new span
+func Parse(traceparent string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	// Future versions may append fields, version 00 must have exactly four
+	version, traceId, parentId, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == traceparentVersion && len(parts) != 4) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	if !isValidId(traceId, 32) || !isValidId(parentId, 16) || !isHex(flags, 2) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	decoded, _ := hex.DecodeString(flags)
+
+	return TraceContext{
+		TraceId:  traceId,
+		SpanId:   randomHex(8),
+		ParentId: parentId,
+		Flags:    decoded[0],
+	}, nil
+}
+
+// Extract reads the trace context sent by the caller in traceparent, tracestate and X-Request-Id.
+// ok is false when header has neither a valid traceparent nor a valid request id, the returned
+// context then starts a trace. A request id that is too long or has characters other than
+// letters, digits and "-_.:" is replaced, it is echoed back and written to every log line.
+func Extract(header http.Header) (trace TraceContext, ok bool) {
+	requestId := strings.TrimSpace(header.Get(HeaderRequestId))
+	if !isValidRequestId(requestId) {
+		requestId = ""
+	}
+
+	trace, err := Parse(header.Get(HeaderTraceparent))
+	if err != nil {
+		if requestId == "" {
+			return New(), false
+		}
+		return FromRequestId(requestId), true
+	}
+
+	trace.TraceState = strings.Join(header.Values(HeaderTracestate), ",")
+	if len(trace.TraceState) > maxTracestateLength {
+		trace.TraceState = ""
+	}
+
+	trace.RequestId = requestId
+	if trace.RequestId == "" {
+		trace.RequestId = requestIdOf(trace.TraceId)
+	}
+
+	return trace, true
+}
+
+// Traceparent formats the W3C traceparent header of our outgoing calls
+func (t TraceContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, t.TraceId, t.SpanId, t.Flags)
+}
+
+// Sampled reports whether the caller recorded the trace
+func (t TraceContext) Sampled() bool {
+	return t.Flags&flagSampled != 0
+}
+
+// Inject sets the trace context headers of an outgoing call, keeping a X-Request-Id already set.
+// New Relic replaces traceparent and tracestate with its own span when it instruments the call.
+func (t TraceContext) Inject(header http.Header) {
+	for key, value := range t.Headers() {
+		if key == HeaderRequestId && header.Get(HeaderRequestId) != "" {
+			continue
+		}
+		header.Set(key, value)
+	}
+}
+
+// Headers returns the trace context headers, for transports other than HTTP
+func (t TraceContext) Headers() map[string]string {
+	headers := map[string]string{
+		HeaderTraceparent: t.Traceparent(),
+	}
+
+	if t.TraceState != "" {
+		headers[HeaderTracestate] = t.TraceState
+	}
+
+	if t.RequestId != "" {
+		headers[HeaderRequestId] = t.RequestId
+	}
+
+	return headers
+}
+
+// WithNewRelic aligns t with the trace of txn, so logs and New Relic share the trace and span ids.
+// A trace started here takes the New Relic trace id, which already continues any incoming
+// newrelic header. When the caller sent neither traceparent nor X-Request-Id the request id
+// is derived again from the new trace id, so callers must echo RequestId after this.
+func (t TraceContext) WithNewRelic(txn *newrelic.Transaction) TraceContext {
+	return t.withTraceMetadata(txn.GetTraceMetadata())
+}
+
+func (t TraceContext) withTraceMetadata(metadata newrelic.TraceMetadata) TraceContext {
+	if !isValidId(metadata.TraceID, 32) || !isValidId(metadata.SpanID, 16) {
+		return t
+	}
+
+	if t.ParentId == "" {
+		t.TraceId = metadata.TraceID
+		if t.generatedRequestId {
+			t.RequestId = requestIdOf(t.TraceId)
+		}
+	}
+
+	if t.TraceId == metadata.TraceID {
+		t.SpanId = metadata.SpanID
+	}
+
+	return t
+}
+
+// NewContext returns a copy of ctx carrying trace
+func NewContext(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, constant.CtxTraceContextKey, trace)
+}
+
+// FromContext returns the trace context stored with NewContext
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(constant.CtxTraceContextKey).(TraceContext)
+	return trace, ok
+}
+
+// requestIdOf formats a trace id as a uuid, the reverse of FromRequestId
+func requestIdOf(traceId string) string {
+	id, err := uuid.Parse(traceId)
+	if err != nil {
+		return traceId
+	}
+	return id.String()
+}
+
+func isValidRequestId(requestId string) bool {
+	if len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range requestId {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && !strings.ContainsRune("-_.:", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidId reports whether id is lowercase hex of size characters and not all zeros
+func isValidId(id string, size int) bool {
+	return isHex(id, size) && strings.Trim(id, "0") != ""
+}
+
+func isHex(value string, size int) bool {
+	if len(value) != size {
+		return false
+	}
+
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(size int) string {
+	buf := make([]byte, size)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,109 @@
+package traceContext_test
+
+import (
+	"boilerplate-service/pkg/traceContext"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErr     bool
+	}{
+		{name: "Valid", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Future Version With Extra Field", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Version 00 With Extra Field", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{name: "Zero Trace Id", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "Upper Case", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", wantErr: true},
+		{name: "Invalid Version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "Empty", traceparent: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := traceContext.Parse(tt.traceparent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if trace.TraceId != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.ParentId != "00f067aa0ba902b7" {
+				t.Errorf("Parse() = %+v", trace)
+			}
+			if trace.SpanId == trace.ParentId || len(trace.SpanId) != 16 {
+				t.Errorf("SpanId = %q, want a new span id", trace.SpanId)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("Traceparent Continues The Trace", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(traceContext.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		header.Set(traceContext.HeaderTracestate, "congo=t61rcWkgMzE")
+
+		trace, ok := traceContext.Extract(header)
+		if !ok {
+			t.Fatal("Extract() ok = false")
+		}
+		if trace.RequestId != "4bf92f35-77b3-4da6-a3ce-929d0e0e4736" {
+			t.Errorf("RequestId = %q, want the uuid form of the trace id", trace.RequestId)
+		}
+
+		outgoing := http.Header{}
+		trace.Inject(outgoing)
+		traceparent := outgoing.Get(traceContext.HeaderTraceparent)
+		if !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+trace.SpanId) {
+			t.Errorf("traceparent = %q", traceparent)
+		}
+		if outgoing.Get(traceContext.HeaderTracestate) != "congo=t61rcWkgMzE" {
+			t.Errorf("tracestate = %q", outgoing.Get(traceContext.HeaderTracestate))
+		}
+	})
+
+	t.Run("Uuid Request Id Becomes The Trace Id", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(traceContext.HeaderRequestId, "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+
+		trace, ok := traceContext.Extract(header)
+		if !ok || trace.TraceId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Extract() = %+v, %v", trace, ok)
+		}
+	})
+
+	t.Run("Invalid Request Id Is Replaced", func(t *testing.T) {
+		for _, requestId := range []string{"id\r\nX-Injected: 1", "<script>", strings.Repeat("a", 129)} {
+			header := http.Header{}
+			header[traceContext.HeaderRequestId] = []string{requestId}
+
+			trace, ok := traceContext.Extract(header)
+			if ok || trace.RequestId == requestId || trace.RequestId == "" {
+				t.Errorf("Extract(%q) = %+v, %v, want a new request id", requestId, trace, ok)
+			}
+		}
+	})
+
+	t.Run("Invalid Request Id Next To A Traceparent", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(traceContext.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		header.Set(traceContext.HeaderRequestId, "a b")
+
+		trace, ok := traceContext.Extract(header)
+		if !ok || trace.RequestId != "4bf92f35-77b3-4da6-a3ce-929d0e0e4736" {
+			t.Errorf("Extract() = %+v, %v, want the uuid form of the trace id", trace, ok)
+		}
+	})
+
+	t.Run("Missing Headers Start A Trace", func(t *testing.T) {
+		trace, ok := traceContext.Extract(http.Header{})
+		if ok || len(trace.TraceId) != 32 || trace.RequestId == "" || !trace.Sampled() {
+			t.Errorf("Extract() = %+v, %v", trace, ok)
+		}
+	})
+}
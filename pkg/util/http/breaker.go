@@ -0,0 +1,186 @@
+package httputil
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type BreakerConfig struct {
+	// FailureRatio opens the breaker once failures / requests within Window reach it
+	FailureRatio float64
+	// MinRequests is the number of requests within Window before the ratio is considered
+	MinRequests int
+	Window      time.Duration
+	// OpenDuration is how long requests are rejected before probing the upstream again
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of probes let through, and that must succeed to close again
+	HalfOpenProbes int
+}
+
+const (
+	defaultBreakerFailureRatio   = 0.5
+	defaultBreakerMinRequests    = 20
+	defaultBreakerWindow         = time.Minute
+	defaultBreakerOpenDuration   = 30 * time.Second
+	defaultBreakerHalfOpenProbes = 1
+)
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.FailureRatio == 0 {
+		c.FailureRatio = defaultBreakerFailureRatio
+	}
+
+	if c.MinRequests == 0 {
+		c.MinRequests = defaultBreakerMinRequests
+	}
+
+	if c.Window == 0 {
+		c.Window = defaultBreakerWindow
+	}
+
+	if c.OpenDuration == 0 {
+		c.OpenDuration = defaultBreakerOpenDuration
+	}
+
+	if c.HalfOpenProbes == 0 {
+		c.HalfOpenProbes = defaultBreakerHalfOpenProbes
+	}
+
+	return c
+}
+
+// breaker is the circuit breaker of a single upstream host. Every state change starts a new
+// generation so results of requests sent before it don't count against the new state.
+type breaker struct {
+	config   BreakerConfig
+	onChange func(from, to BreakerState)
+
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	probes      int
+	successes   int
+}
+
+func newBreaker(config BreakerConfig, onChange func(from, to BreakerState)) *breaker {
+	return &breaker{
+		config:      config,
+		onChange:    onChange,
+		state:       BreakerClosed,
+		windowStart: time.Now(),
+	}
+}
+
+// allow reserves a request, it returns the generation to report the result with
+func (b *breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.config.OpenDuration {
+			return 0, ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen, now)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		b.probes++
+	default:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.resetCounts(now)
+		}
+	}
+
+	b.requests++
+	return b.generation, nil
+}
+
+// done reports the result of a request reserved with allow
+func (b *breaker) done(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	now := time.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		if !success {
+			b.setState(BreakerOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		if success {
+			return
+		}
+		b.failures++
+		if b.requests >= b.config.MinRequests && float64(b.failures)/float64(b.requests) >= b.config.FailureRatio {
+			b.setState(BreakerOpen, now)
+		}
+	}
+}
+
+// forget drops a request reserved with allow without counting its result
+func (b *breaker) forget(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch {
+	case b.state == BreakerHalfOpen && b.probes > 0:
+		b.probes--
+	case b.state == BreakerClosed && b.requests > 0:
+		b.requests--
+	}
+}
+
+func (b *breaker) setState(state BreakerState, now time.Time) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.resetCounts(now)
+
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
+
+func (b *breaker) resetCounts(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+}
@@ -0,0 +1,52 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBulkheadFull = errors.New("too many concurrent requests to upstream")
+
+// bulkhead caps the number of in-flight requests to a single upstream host,
+// so a slow dependency can't hold every connection and goroutine of the service.
+type bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+func newBulkhead(maxConcurrent int, maxWait time.Duration) *bulkhead {
+	return &bulkhead{
+		slots:   make(chan struct{}, maxConcurrent),
+		maxWait: maxWait,
+	}
+}
+
+// acquire waits up to maxWait for a slot, the returned func gives it back
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	default:
+	}
+
+	if b.maxWait <= 0 {
+		return nil, ErrBulkheadFull
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+}
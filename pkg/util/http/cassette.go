@@ -0,0 +1,310 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var ErrCassetteNoMatch = errors.New("no recorded interaction matches the request")
+
+type CassetteMode int
+
+const (
+	// CassetteReplay serves recorded interactions only, unknown requests fail with ErrCassetteNoMatch
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends every request upstream and records it, replacing the cassette on Save
+	CassetteRecord
+	// CassetteAuto replays when the cassette file exists and records otherwise
+	CassetteAuto
+)
+
+const redactedValue = "[REDACTED]"
+
+type CassetteConfig struct {
+	// Path is the cassette file, usually under the package's testdata directory
+	Path string
+	Mode CassetteMode
+	// Transport sends requests while recording, defaults to http.DefaultTransport
+	Transport http.RoundTripper
+
+	// RedactHeaders are replaced in both requests and responses before they are stored
+	RedactHeaders []string
+	// RedactJSONFields are dotted paths, e.g. "card.number", replaced in JSON bodies
+	RedactJSONFields []string
+	// RedactPatterns are replaced anywhere in bodies
+	RedactPatterns []*regexp.Regexp
+	// IgnoreBody matches requests on method and URL only
+	IgnoreBody bool
+}
+
+type cassetteFile struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   recordedBody `json:"body"`
+}
+
+type recordedResponse struct {
+	Status int          `json:"status"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   recordedBody `json:"body"`
+}
+
+// recordedBody is kept as text when possible so cassettes are readable in review
+type recordedBody []byte
+
+func (b recordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(map[string]string{"text": string(b)})
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *recordedBody) UnmarshalJSON(raw []byte) error {
+	var body map[string]string
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return err
+	}
+
+	if encoded, ok := body["base64"]; ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		*b = decoded
+		return err
+	}
+
+	*b = recordedBody(body["text"])
+	return nil
+}
+
+// Cassette is a RoundTripper recording real exchanges to a file and replaying them deterministically,
+// so tests against third parties run offline. Pass it as Config.Transport of the client under test.
+type Cassette struct {
+	config    CassetteConfig
+	recording bool
+
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+func NewCassette(config CassetteConfig) (*Cassette, error) {
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	c := &Cassette{config: config}
+
+	switch config.Mode {
+	case CassetteRecord:
+		c.recording = true
+	case CassetteAuto:
+		_, err := os.Stat(config.Path)
+		c.recording = errors.Is(err, os.ErrNotExist)
+	}
+
+	if c.recording {
+		return c, nil
+	}
+
+	raw, err := os.ReadFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", config.Path, err)
+	}
+
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(file.Interactions))
+
+	return c, nil
+}
+
+// Recording reports whether requests are sent upstream
+func (c *Cassette) Recording() bool {
+	return c.recording
+}
+
+func (c *Cassette) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := recordedRequest{
+		Method: request.Method,
+		URL:    request.URL.String(),
+		Header: c.redactHeader(request.Header),
+		Body:   c.redactBody(body),
+	}
+
+	if c.recording {
+		return c.record(request, recorded)
+	}
+
+	return c.replay(request, recorded)
+}
+
+func (c *Cassette) record(request *http.Request, recorded recordedRequest) (*http.Response, error) {
+	response, err := c.config.Transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction{
+		Request: recorded,
+		Response: recordedResponse{
+			Status: response.StatusCode,
+			Header: c.redactHeader(response.Header),
+			Body:   c.redactBody(body),
+		},
+	})
+	c.mu.Unlock()
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// replay serves the first unused interaction matching method, URL and body, in recorded order
+func (c *Cassette) replay(request *http.Request, recorded recordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, candidate := range c.interactions {
+		if c.used[i] || !c.matches(candidate.Request, recorded) {
+			continue
+		}
+		c.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", candidate.Response.Status, http.StatusText(candidate.Response.Status)),
+			StatusCode:    candidate.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        candidate.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(candidate.Response.Body)),
+			ContentLength: int64(len(candidate.Response.Body)),
+			Request:       request,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrCassetteNoMatch, recorded.Method, recorded.URL)
+}
+
+func (c *Cassette) matches(candidate, recorded recordedRequest) bool {
+	if candidate.Method != recorded.Method || candidate.URL != recorded.URL {
+		return false
+	}
+	return c.config.IgnoreBody || bytes.Equal(candidate.Body, recorded.Body)
+}
+
+// Save writes recorded interactions to the cassette file, it is a no-op while replaying
+func (c *Cassette) Save() error {
+	if !c.recording {
+		return nil
+	}
+
+	c.mu.Lock()
+	raw, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.config.Path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.config.Path, append(raw, '\n'), 0o644)
+}
+
+func (c *Cassette) redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range c.config.RedactHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, redactedValue)
+		}
+	}
+	return redacted
+}
+
+func (c *Cassette) redactBody(body []byte) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if len(c.config.RedactJSONFields) > 0 {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err == nil {
+			redacted := false
+			for _, path := range c.config.RedactJSONFields {
+				redacted = redactJSONPath(doc, strings.Split(path, ".")) || redacted
+			}
+			// Bodies without sensitive fields keep their original formatting and key order
+			if raw, err := json.Marshal(doc); err == nil && redacted {
+				body = raw
+			}
+		}
+	}
+
+	for _, pattern := range c.config.RedactPatterns {
+		body = pattern.ReplaceAll(body, []byte(redactedValue))
+	}
+
+	return body
+}
+
+// redactJSONPath replaces the value at path, arrays on the way are walked element by element.
+// It reports whether anything was replaced.
+func redactJSONPath(node interface{}, path []string) bool {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			v[path[0]] = redactedValue
+			return true
+		}
+		return redactJSONPath(child, path[1:])
+	case []interface{}:
+		redacted := false
+		for _, item := range v {
+			redacted = redactJSONPath(item, path) || redacted
+		}
+		return redacted
+	default:
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package httputil_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	httputil "boilerplate-service/pkg/util/http"
+)
+
+var chargeCassetteConfig = httputil.CassetteConfig{
+	RedactHeaders:    []string{"Authorization"},
+	RedactJSONFields: []string{"card_number"},
+	RedactPatterns:   []*regexp.Regexp{regexp.MustCompile(`tok_[a-z0-9]+`)},
+}
+
+func TestCassetteReplay(t *testing.T) {
+	config := chargeCassetteConfig
+	config.Path = "testdata/cassettes/payment_charge.json"
+
+	cassette, err := httputil.NewCassette(config)
+	if err != nil {
+		t.Fatalf("NewCassette() error = %v", err)
+	}
+	client := httputil.New(httputil.Config{Transport: cassette})
+
+	charge := map[string]interface{}{"amount": 150000, "currency": "IDR", "card_number": "4111111111111111"}
+	header := map[string]string{"Authorization": "Bearer live-secret"}
+
+	res, code, err := client.RequestHitAPI(context.Background(), http.MethodPost, "https://payment.example.com/v1/charges", charge, header)
+	if err != nil || code != http.StatusCreated || !strings.Contains(string(res), `"ch_1"`) {
+		t.Fatalf("RequestHitAPI() = %s, %v, %v", res, code, err)
+	}
+
+	// Every interaction is served once
+	_, _, err = client.RequestHitAPI(context.Background(), http.MethodPost, "https://payment.example.com/v1/charges", charge, header)
+	if !errors.Is(err, httputil.ErrCassetteNoMatch) {
+		t.Errorf("RequestHitAPI() second call error = %v, want %v", err, httputil.ErrCassetteNoMatch)
+	}
+}
+
+func TestCassetteRecord(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"tok_abc123","status":"ok"}`))
+	}))
+
+	config := chargeCassetteConfig
+	config.Path = filepath.Join(t.TempDir(), "cassettes", "tokenize.json")
+	config.Mode = httputil.CassetteAuto
+
+	recorder, err := httputil.NewCassette(config)
+	if err != nil || !recorder.Recording() {
+		t.Fatalf("NewCassette() recording = %v, error = %v", recorder.Recording(), err)
+	}
+	_, _, err = httputil.New(httputil.Config{Transport: recorder}).RequestHitAPI(context.Background(), http.MethodGet, mockServer.URL+"/tokens", nil, nil)
+	if err != nil {
+		t.Fatalf("RequestHitAPI() while recording error = %v", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The upstream is gone, the cassette now answers on its own
+	mockServer.Close()
+
+	player, err := httputil.NewCassette(config)
+	if err != nil || player.Recording() {
+		t.Fatalf("NewCassette() recording = %v, error = %v", player.Recording(), err)
+	}
+	res, _, err := httputil.New(httputil.Config{Transport: player}).RequestHitAPI(context.Background(), http.MethodGet, mockServer.URL+"/tokens", nil, nil)
+	if err != nil {
+		t.Fatalf("RequestHitAPI() while replaying error = %v", err)
+	}
+	if string(res) != `{"token":"[REDACTED]","status":"ok"}` {
+		t.Errorf("RequestHitAPI() replayed body = %s", res)
+	}
+}
@@ -0,0 +1,447 @@
+package httputil
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/newRelicExt"
+	"boilerplate-service/pkg/traceContext"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.uber.org/zap"
+)
+
+type IClient interface {
+	// Do sends request, retrying it per Config. A non 2xx response is returned together
+	// with an *HTTPError whose body was already read, so the caller doesn't have to close it.
+	Do(request *http.Request) (*http.Response, error)
+	// RequestHitAPI sends data as JSON ([]byte and io.Reader are sent as is) and returns the response body.
+	RequestHitAPI(ctx context.Context, method, uri string, data interface{}, header map[string]string) ([]byte, int, error)
+}
+
+type Config struct {
+	// Timeout bounds each attempt, the overall deadline comes from the request context
+	Timeout time.Duration
+
+	// MaxRetries is the number of retries after the first attempt, 0 disables retries
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the backoff, a Retry-After longer than it ends the retries
+	RetryMaxDelay time.Duration
+	// RetryStatuses are the response codes worth retrying, defaults to 429, 502, 503 and 504
+	RetryStatuses []int
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// Transport is the base transport, defaults to a pooled transport shared by the client
+	Transport http.RoundTripper
+	// Signer signs every request for service to service calls
+	Signer *Signer
+
+	// Breaker enables a circuit breaker per upstream host, nil disables it
+	Breaker *BreakerConfig
+	// MaxConcurrentPerHost caps in-flight requests per upstream host, 0 disables the bulkhead
+	MaxConcurrentPerHost int
+	// BulkheadMaxWait is how long a request waits for a free slot before failing with ErrBulkheadFull
+	BulkheadMaxWait time.Duration
+
+	// Logger and NewRelic receive circuit breaker and bulkhead events, both are optional
+	Logger   logger.ILogger
+	NewRelic newRelicExt.INewRelicExt
+}
+
+type client struct {
+	config        Config
+	httpClient    *http.Client
+	retryStatuses map[int]bool
+
+	mu        sync.Mutex
+	breakers  map[string]*breaker
+	bulkheads map[string]*bulkhead
+}
+
+const (
+	defaultTimeout             = 30 * time.Second
+	defaultRetryBaseDelay      = 100 * time.Millisecond
+	defaultRetryMaxDelay       = 5 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	// maxErrorBodySize caps the error body kept in HTTPError
+	maxErrorBodySize = 64 << 10
+)
+
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func New(config Config) IClient {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.RetryBaseDelay == 0 {
+		config.RetryBaseDelay = defaultRetryBaseDelay
+	}
+
+	if config.RetryMaxDelay == 0 {
+		config.RetryMaxDelay = defaultRetryMaxDelay
+	}
+
+	if config.RetryStatuses == nil {
+		config.RetryStatuses = defaultRetryStatuses
+	}
+
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = defaultMaxIdleConns
+	}
+
+	if config.MaxIdleConnsPerHost == 0 {
+		config.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	transport := config.Transport
+	if transport == nil {
+		transport = newTransport(config)
+	}
+
+	if config.Signer != nil {
+		transport = config.Signer.RoundTripper(transport)
+	}
+
+	if config.Breaker != nil {
+		breakerConfig := config.Breaker.withDefaults()
+		config.Breaker = &breakerConfig
+	}
+
+	retryStatuses := make(map[int]bool, len(config.RetryStatuses))
+	for _, status := range config.RetryStatuses {
+		retryStatuses[status] = true
+	}
+
+	return &client{
+		config: config,
+		httpClient: &http.Client{
+			Transport: newrelic.NewRoundTripper(transport),
+		},
+		retryStatuses: retryStatuses,
+		breakers:      map[string]*breaker{},
+		bulkheads:     map[string]*bulkhead{},
+	}
+}
+
+func newTransport(config Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+func (c *client) Do(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	// The New Relic round tripper reads the transaction from newrelic's own context key
+	if txn := newRelicExt.GetTxnFromCtx(ctx); txn != nil && newrelic.FromContext(ctx) == nil {
+		request = newrelic.RequestWithTransactionContext(request, txn)
+	}
+
+	// Propagates traceparent and X-Request-Id, the New Relic round tripper replaces traceparent
+	// with its external segment when there is a transaction
+	if trace, ok := traceContext.FromContext(ctx); ok {
+		request = request.Clone(ctx)
+		trace.Inject(request.Header)
+	}
+
+	body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	retryable := isIdempotent(request)
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.attempt(request, body)
+
+		if attempt >= c.config.MaxRetries || !retryable || !c.shouldRetry(ctx, response, err) {
+			return response, err
+		}
+
+		delay := backoff(attempt, c.config.RetryBaseDelay, c.config.RetryMaxDelay)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				// The upstream asked for a longer pause than we are willing to hold the caller for
+				if retryAfter > c.config.RetryMaxDelay {
+					return response, err
+				}
+				delay = retryAfter
+			}
+		}
+
+		// Don't sleep past the caller's deadline, the next attempt would fail anyway
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return response, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one copy of request bounded by config.Timeout, through the bulkhead and circuit breaker of its host
+func (c *client) attempt(request *http.Request, body []byte) (*http.Response, error) {
+	host := request.URL.Host
+
+	release := func() {}
+	if bulkhead := c.bulkheadFor(host); bulkhead != nil {
+		var err error
+		release, err = bulkhead.acquire(request.Context())
+		if err != nil {
+			if errors.Is(err, ErrBulkheadFull) {
+				c.logEvent(request.Context(), "Bulkhead rejected request", host, zap.Int("max_concurrent", c.config.MaxConcurrentPerHost))
+				c.recordMetric("Bulkhead/"+host+"/rejected", 1)
+			}
+			return nil, err
+		}
+	}
+
+	breaker := c.breakerFor(host)
+	var generation uint64
+	if breaker != nil {
+		var err error
+		generation, err = breaker.allow()
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	response, err := c.send(request, body, release)
+
+	if breaker != nil {
+		switch {
+		case errors.Is(err, context.Canceled) && request.Context().Err() != nil:
+			// The caller gave up, that says nothing about the upstream
+			breaker.forget(generation)
+		default:
+			breaker.done(generation, err == nil || (response != nil && response.StatusCode < 500 && response.StatusCode != http.StatusTooManyRequests))
+		}
+	}
+
+	return response, err
+}
+
+// send does the round trip, release is called once the response body is done with
+func (c *client) send(request *http.Request, body []byte, release func()) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(request.Context(), c.config.Timeout)
+	headersReceived := func() bool { return true }
+
+	if withoutBodyTimeout, _ := request.Context().Value(ctxKeyWithoutBodyTimeout).(bool); withoutBodyTimeout {
+		cancel()
+		ctx, cancel = context.WithCancel(request.Context())
+		timer := time.AfterFunc(c.config.Timeout, cancel)
+		headersReceived = timer.Stop
+	}
+
+	done := func() {
+		cancel()
+		release()
+	}
+
+	request = request.Clone(ctx)
+	if body != nil {
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	response, err := c.httpClient.Do(request)
+	if !headersReceived() && err != nil {
+		err = fmt.Errorf("%w: no response headers within %s", context.DeadlineExceeded, c.config.Timeout)
+	}
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		defer done()
+		defer response.Body.Close()
+
+		errBody, err := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		if err != nil {
+			return nil, err
+		}
+		response.Body = io.NopCloser(bytes.NewReader(errBody))
+
+		return response, &HTTPError{
+			Method:     request.Method,
+			URL:        request.URL.Redacted(),
+			StatusCode: response.StatusCode,
+			Header:     response.Header,
+			Body:       errBody,
+		}
+	}
+
+	// The attempt context and bulkhead slot are held as long as the body is being read
+	response.Body = &closeNotifier{ReadCloser: response.Body, onClose: done}
+	return response, nil
+}
+
+type ctxKey int
+
+const ctxKeyWithoutBodyTimeout ctxKey = iota
+
+// WithoutBodyTimeout returns a copy of ctx whose requests are bounded by Config.Timeout only until
+// the response headers arrive, reading the body then lasts as long as ctx allows. Stream and
+// Download use it so long downloads aren't cut off.
+func WithoutBodyTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyWithoutBodyTimeout, true)
+}
+
+func (c *client) shouldRetry(ctx context.Context, response *http.Response, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+		return false
+	}
+
+	if response != nil {
+		return c.retryStatuses[response.StatusCode]
+	}
+
+	return err != nil
+}
+
+func (c *client) RequestHitAPI(
+	ctx context.Context,
+	method string,
+	uri string,
+	data interface{},
+	header map[string]string,
+) (
+	res []byte,
+	code int,
+	err error,
+) {
+	segment := newRelicExt.
+		GetTxnFromCtx(ctx).
+		StartSegment("util/http_request.go/RequestHitAPI")
+	defer segment.End()
+
+	request, err := assertTypeRequest(ctx, data, method, uri)
+	if err != nil {
+		return res, code, err
+	}
+
+	for k, v := range header {
+		request.Header.Set(k, v)
+	}
+
+	response, err := c.Do(request)
+	if response == nil {
+		return res, code, err
+	}
+
+	defer response.Body.Close()
+
+	code = response.StatusCode
+
+	// Error bodies were already read into the HTTPError
+	if httpErr, ok := err.(*HTTPError); ok {
+		return httpErr.Body, code, err
+	}
+
+	res, err = io.ReadAll(response.Body)
+	return res, code, err
+}
+
+func (c *client) breakerFor(host string) *breaker {
+	if c.config.Breaker == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = newBreaker(*c.config.Breaker, func(from, to BreakerState) {
+			c.logEvent(context.Background(), "Circuit breaker state changed", host, zap.String("from", string(from)), zap.String("to", string(to)))
+			c.recordMetric("CircuitBreaker/"+host+"/"+string(to), 1)
+		})
+		c.breakers[host] = b
+	}
+
+	return b
+}
+
+func (c *client) bulkheadFor(host string) *bulkhead {
+	if c.config.MaxConcurrentPerHost <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.bulkheads[host]
+	if !ok {
+		b = newBulkhead(c.config.MaxConcurrentPerHost, c.config.BulkheadMaxWait)
+		c.bulkheads[host] = b
+	}
+
+	return b
+}
+
+func (c *client) logEvent(ctx context.Context, msg, host string, fields ...zap.Field) {
+	if c.config.Logger != nil {
+		c.config.Logger.Warn(ctx, msg, append(fields, zap.String("host", host))...)
+	}
+}
+
+// recordMetric reports to New Relic, the agent prefixes the name with Custom/
+func (c *client) recordMetric(name string, value float64) {
+	if c.config.NewRelic != nil {
+		c.config.NewRelic.RecordCustomMetric("HttpClient/"+name, value)
+	}
+}
+
+type closeNotifier struct {
+	io.ReadCloser
+	onClose func()
+	once    sync.Once
+}
+
+func (b *closeNotifier) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.onClose)
+	return err
+}
@@ -0,0 +1,246 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type BodyEncoding int
+
+const (
+	// EncodingJSON sends the body as application/json, it is the default
+	EncodingJSON BodyEncoding = iota
+	// EncodingForm sends url.Values, map[string]string or a struct with `form` tags as a urlencoded form
+	EncodingForm
+	// EncodingMultipart sends a Multipart body as multipart/form-data
+	EncodingMultipart
+)
+
+// Multipart is a multipart/form-data body
+type Multipart struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Content     io.Reader
+}
+
+// encodeBody returns the encoded body and its content type, a nil body is sent empty
+func encodeBody(body interface{}, encoding BodyEncoding) (io.Reader, string, error) {
+	if isNil(body) {
+		return nil, "", nil
+	}
+
+	switch encoding {
+	case EncodingForm:
+		values, err := Values(body, "form")
+		if err != nil {
+			return nil, "", err
+		}
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+	case EncodingMultipart:
+		return encodeMultipart(body)
+	default:
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(raw), "application/json", nil
+	}
+}
+
+func encodeMultipart(body interface{}) (io.Reader, string, error) {
+	var parts Multipart
+	switch b := body.(type) {
+	case Multipart:
+		parts = b
+	case *Multipart:
+		parts = *b
+	default:
+		return nil, "", fmt.Errorf("multipart body must be httputil.Multipart, got %T", body)
+	}
+
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	for name, value := range parts.Fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, file := range parts.Files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(file.FieldName), escapeQuotes(file.FileName)))
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf, writer.FormDataContentType(), nil
+}
+
+// Values converts url.Values, map[string]string, map[string][]string or a struct into url.Values.
+// Struct fields are named by tag (e.g. `query:"page"` or `form:"page"`), "-" skips a field,
+// ",omitempty" skips zero values and slices become repeated keys.
+func Values(input interface{}, tag string) (url.Values, error) {
+	switch v := input.(type) {
+	case nil:
+		return url.Values{}, nil
+	case url.Values:
+		return v, nil
+	case map[string][]string:
+		return url.Values(v), nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(input)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %T as url values", input)
+	}
+
+	values := url.Values{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatValue(fv.Index(j)))
+			}
+			continue
+		}
+
+		values.Add(name, formatValue(fv))
+	}
+
+	return values, nil
+}
+
+// BuildURL appends query to base, keeping the query already in base
+func BuildURL(base string, query interface{}) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := Values(query, "query")
+	if err != nil {
+		return "", err
+	}
+
+	merged := u.Query()
+	for key, vs := range values {
+		for _, v := range vs {
+			merged.Add(key, v)
+		}
+	}
+	u.RawQuery = merged.Encode()
+
+	return u.String(), nil
+}
+
+func formatValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	case reflect.Struct:
+		// Request[struct{}] is how callers spell "no body"
+		return rv.NumField() == 0
+	default:
+		return false
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
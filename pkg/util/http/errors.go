@@ -0,0 +1,28 @@
+package httputil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is returned for responses outside the 2xx range
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	// Body is the start of the response body, at most 64KiB
+	Body []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// AsHTTPError unwraps err into an *HTTPError
+func AsHTTPError(err error) (*HTTPError, bool) {
+	var httpErr *HTTPError
+	ok := errors.As(err, &httpErr)
+	return httpErr, ok
+}
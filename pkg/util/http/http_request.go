@@ -1,17 +1,42 @@
 package httputil
 
 import (
-	"boilerplate-service/pkg/newRelicExt"
 	"bytes"
 	"context"
+	"sync"
 
 	"encoding/json"
 	"io"
 	"net/http"
+)
 
-	"github.com/newrelic/go-agent/v3/newrelic"
+var (
+	defaultClientMu sync.Mutex
+	defaultClient   IClient
 )
 
+// SetDefaultClient replaces the shared client used by RequestHitAPI, e.g. with one signing its requests
+func SetDefaultClient(client IClient) {
+	defaultClientMu.Lock()
+	defaultClient = client
+	defaultClientMu.Unlock()
+}
+
+// DefaultClient returns the shared client used by RequestHitAPI. Unless SetDefaultClient was called
+// it retries twice with the default settings.
+func DefaultClient() IClient {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+
+	if defaultClient == nil {
+		defaultClient = New(Config{MaxRetries: 2})
+	}
+	return defaultClient
+}
+
+// RequestHitAPI sends data with the shared DefaultClient.
+//
+// Returns the response body and status code, err is an *HTTPError for non 2xx responses.
 func RequestHitAPI(
 	ctx context.Context,
 	method string,
@@ -23,55 +48,29 @@ func RequestHitAPI(
 	code int,
 	err error,
 ) {
-	segment := newRelicExt.
-		GetTxnFromCtx(ctx).
-		StartSegment("util/http_request.go/RequestHitAPI")
-	defer segment.End()
-
-	httpClient := &http.Client{}
-	httpClient.Transport = newrelic.NewRoundTripper(httpClient.Transport)
-	request, err := assertTypeRequest(data, method, uri)
-	if err != nil {
-		return res, code, err
-	}
+	return DefaultClient().RequestHitAPI(ctx, method, uri, data, header)
+}
 
-	for k, v := range header {
-		request.Header.Add(k, v)
+func assertTypeRequest(ctx context.Context, data interface{}, method string, uri string) (request *http.Request, err error) {
+	switch body := data.(type) {
+	case nil:
+		return http.NewRequestWithContext(ctx, method, uri, nil)
+	case []byte:
+		return http.NewRequestWithContext(ctx, method, uri, bytes.NewReader(body))
+	case io.Reader:
+		return http.NewRequestWithContext(ctx, method, uri, body)
 	}
 
-	request.Header.Set("Content-type", "application/json")
-
-	response, err := httpClient.Do(request)
+	paramReq, err := json.Marshal(data)
 	if err != nil {
-		return res, code, err
+		return nil, err
 	}
 
-	defer response.Body.Close()
-
-	code = response.StatusCode
-
-	res, err = io.ReadAll(response.Body)
+	request, err = http.NewRequestWithContext(ctx, method, uri, bytes.NewBuffer(paramReq))
 	if err != nil {
-		return res, code, err
-	}
-
-	if isHttpError := code != http.StatusOK && code != http.StatusCreated; isHttpError {
-		var errRes map[string]interface{}
-		err := json.Unmarshal(res, &errRes)
-		return res, code, err
+		return nil, err
 	}
-
-	return res, code, err
-}
-
-func assertTypeRequest(data interface{}, method string, uri string) (request *http.Request, err error) {
-	if data == nil {
-		request, err = http.NewRequest(method, uri, nil)
-		return
-	}
-
-	paramReq, _ := json.Marshal(data)
-	request, err = http.NewRequest(method, uri, bytes.NewBuffer(paramReq))
+	request.Header.Set("Content-Type", "application/json")
 
 	return
 }
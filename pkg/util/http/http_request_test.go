@@ -2,6 +2,7 @@ package httputil_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -94,3 +95,136 @@ func TestRequestHitAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		failures     int
+		failStatus   int
+		retryAfter   string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "Retry GET Until Success",
+			method:       http.MethodGet,
+			failures:     2,
+			failStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 3,
+			wantErr:      false,
+		},
+		{
+			name:         "Give Up After MaxRetries",
+			method:       http.MethodGet,
+			failures:     5,
+			failStatus:   http.StatusTooManyRequests,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "Give Up When Retry-After Exceeds RetryMaxDelay",
+			method:       http.MethodGet,
+			failures:     1,
+			failStatus:   http.StatusServiceUnavailable,
+			retryAfter:   "3600",
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "No Retry On POST",
+			method:       http.MethodPost,
+			failures:     1,
+			failStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "No Retry On Client Error",
+			method:       http.MethodGet,
+			failures:     1,
+			failStatus:   http.StatusBadRequest,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tt.failures {
+					retryAfter := tt.retryAfter
+					if retryAfter == "" {
+						retryAfter = "0"
+					}
+					w.Header().Set("Retry-After", retryAfter)
+					w.WriteHeader(tt.failStatus)
+					w.Write([]byte(`{"error":"unavailable"}`))
+					return
+				}
+				w.Write([]byte(`{"status":"ok"}`))
+			}))
+			defer mockServer.Close()
+
+			client := httputil.New(httputil.Config{MaxRetries: 2, RetryBaseDelay: time.Millisecond})
+
+			_, code, err := client.RequestHitAPI(context.Background(), tt.method, mockServer.URL, nil, nil)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s: RequestHitAPI() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if httpErr, ok := httputil.AsHTTPError(err); ok && httpErr.StatusCode != code {
+				t.Errorf("%s: HTTPError status = %v, want %v", tt.name, httpErr.StatusCode, code)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%s: attempts = %v, want %v", tt.name, attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	healthy := false
+	attempts := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer mockServer.Close()
+
+	client := httputil.New(httputil.Config{
+		Breaker: &httputil.BreakerConfig{
+			FailureRatio: 0.5,
+			MinRequests:  3,
+			OpenDuration: 50 * time.Millisecond,
+		},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		client.RequestHitAPI(ctx, http.MethodGet, mockServer.URL, nil, nil)
+	}
+
+	_, _, err := client.RequestHitAPI(ctx, http.MethodGet, mockServer.URL, nil, nil)
+	if !errors.Is(err, httputil.ErrCircuitOpen) {
+		t.Fatalf("RequestHitAPI() error = %v, want %v", err, httputil.ErrCircuitOpen)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %v, want 3 while the breaker is open", attempts)
+	}
+
+	healthy = true
+	time.Sleep(60 * time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.RequestHitAPI(ctx, http.MethodGet, mockServer.URL, nil, nil); err != nil {
+			t.Errorf("RequestHitAPI() after recovery error = %v", err)
+		}
+	}
+}
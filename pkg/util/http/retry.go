@@ -0,0 +1,57 @@
+package httputil
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isIdempotent reports whether request can be sent again safely. Non idempotent methods
+// qualify when they carry an Idempotency-Key the upstream deduplicates on.
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return request.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// backoff returns an exponential delay with full jitter for the given attempt, starting at 0
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := max
+	if attempt < 32 {
+		if d := base << attempt; d > 0 && d < max {
+			delay = d
+		}
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
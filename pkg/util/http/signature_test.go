@@ -0,0 +1,77 @@
+package httputil_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	httputil "boilerplate-service/pkg/util/http"
+)
+
+func TestSignature(t *testing.T) {
+	signer := httputil.NewSigner("order-service", "shared-secret")
+
+	tests := []struct {
+		name    string
+		secret  string
+		tamper  func(r *http.Request)
+		wantErr error
+	}{
+		{
+			name:    "Valid Signature",
+			secret:  "shared-secret",
+			tamper:  func(r *http.Request) {},
+			wantErr: nil,
+		},
+		{
+			name:    "Wrong Secret",
+			secret:  "other-secret",
+			tamper:  func(r *http.Request) {},
+			wantErr: httputil.ErrSignatureInvalid,
+		},
+		{
+			name:   "Tampered Path",
+			secret: "shared-secret",
+			tamper: func(r *http.Request) {
+				r.URL.Path = "/internal/orders/other"
+			},
+			wantErr: httputil.ErrSignatureInvalid,
+		},
+		{
+			name:   "Stale Timestamp",
+			secret: "shared-secret",
+			tamper: func(r *http.Request) {
+				r.Header.Set(httputil.HeaderSignatureTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+			},
+			wantErr: httputil.ErrSignatureTimestamp,
+		},
+		{
+			name:   "Missing Nonce",
+			secret: "shared-secret",
+			tamper: func(r *http.Request) {
+				r.Header.Del(httputil.HeaderSignatureNonce)
+			},
+			wantErr: httputil.ErrSignatureMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/internal/orders/sync?force=true", bytes.NewBufferString(`{"id":1}`))
+			if err := signer.Sign(request); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			tt.tamper(request)
+
+			body, _ := io.ReadAll(request.Body)
+			err := httputil.VerifySignature(request, []byte(tt.secret), body, 5*time.Minute)
+			if err != tt.wantErr {
+				t.Errorf("%s: VerifySignature() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package httputil
+
+import (
+	"boilerplate-service/pkg/newRelicExt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Request describes a typed outbound call
+type Request[Req any] struct {
+	Method string
+	URL    string
+	// Query is url.Values, a map or a struct with `query` tags appended to URL
+	Query  interface{}
+	Header map[string]string
+	// Body is encoded per Encoding, leave it nil for requests without body
+	Body     Req
+	Encoding BodyEncoding
+}
+
+// APIError is an HTTPError whose body was decoded into the caller's error type.
+// Body is left zero when the error body isn't valid JSON for E.
+type APIError[E any] struct {
+	*HTTPError
+	Body E
+}
+
+func (e *APIError[E]) Unwrap() error {
+	return e.HTTPError
+}
+
+// Do sends request with client and decodes a 2xx JSON body into Res.
+// Res may be []byte to get the raw body. Non 2xx responses return an *HTTPError.
+func Do[Req, Res any](ctx context.Context, client IClient, request Request[Req]) (Res, error) {
+	var res Res
+
+	segment := newRelicExt.
+		GetTxnFromCtx(ctx).
+		StartSegment("util/http/typed.go/Do")
+	defer segment.End()
+
+	response, err := send(ctx, client, request, "application/json")
+	if err != nil {
+		return res, err
+	}
+	defer response.Body.Close()
+
+	if raw, ok := interface{}(&res).(*[]byte); ok {
+		*raw, err = io.ReadAll(response.Body)
+		return res, err
+	}
+
+	if response.StatusCode == http.StatusNoContent {
+		return res, nil
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&res); err != nil && !errors.Is(err, io.EOF) {
+		return res, fmt.Errorf("decode %s %s response: %w", request.Method, request.URL, err)
+	}
+
+	return res, nil
+}
+
+// DoWithError is Do decoding non 2xx bodies into E, the error is then an *APIError[E]
+func DoWithError[Req, Res, E any](ctx context.Context, client IClient, request Request[Req]) (Res, error) {
+	res, err := Do[Req, Res](ctx, client, request)
+
+	httpErr, ok := AsHTTPError(err)
+	if !ok {
+		return res, err
+	}
+
+	apiErr := &APIError[E]{HTTPError: httpErr}
+	_ = json.Unmarshal(httpErr.Body, &apiErr.Body)
+
+	return res, apiErr
+}
+
+// Stream sends request and hands the 2xx body to fn without buffering it, for large downloads.
+// The client Timeout only bounds the wait for the response headers, ctx bounds reading the body.
+// The body is closed once fn returns.
+func Stream[Req any](ctx context.Context, client IClient, request Request[Req], fn func(response *http.Response) error) error {
+	segment := newRelicExt.
+		GetTxnFromCtx(ctx).
+		StartSegment("util/http/typed.go/Stream")
+	defer segment.End()
+
+	response, err := send(WithoutBodyTimeout(ctx), client, request, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return fn(response)
+}
+
+// Download streams the 2xx body of a GET on uri into w.
+//
+// Returns the number of bytes written.
+func Download(ctx context.Context, client IClient, uri string, header map[string]string, w io.Writer) (int64, error) {
+	var written int64
+	err := Stream(ctx, client, Request[interface{}]{Method: http.MethodGet, URL: uri, Header: header}, func(response *http.Response) error {
+		var err error
+		written, err = io.Copy(w, response.Body)
+		return err
+	})
+	return written, err
+}
+
+func send[Req any](ctx context.Context, client IClient, request Request[Req], accept string) (*http.Response, error) {
+	uri := request.URL
+	if request.Query != nil {
+		var err error
+		if uri, err = BuildURL(uri, request.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	body, contentType, err := encodeBody(request.Body, request.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, method, uri, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		httpRequest.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		httpRequest.Header.Set("Accept", accept)
+	}
+	for k, v := range request.Header {
+		httpRequest.Header.Set(k, v)
+	}
+
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		// Error bodies are already held by the HTTPError
+		if response != nil {
+			response.Body.Close()
+		}
+		return nil, err
+	}
+
+	return response, nil
+}
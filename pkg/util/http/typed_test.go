@@ -0,0 +1,154 @@
+package httputil_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httputil "boilerplate-service/pkg/util/http"
+)
+
+type createOrderRequest struct {
+	Amount int    `json:"amount" form:"amount"`
+	Note   string `json:"note,omitempty" form:"note,omitempty"`
+}
+
+type createOrderResponse struct {
+	Id          string `json:"id"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+	Query       string `json:"query"`
+}
+
+type upstreamError struct {
+	Message string `json:"message"`
+}
+
+func TestDo(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"amount too low"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(createOrderResponse{
+			Id:          "order-1",
+			ContentType: r.Header.Get("Content-Type"),
+			Body:        string(body),
+			Query:       r.URL.RawQuery,
+		})
+	}))
+	defer mockServer.Close()
+
+	client := httputil.New(httputil.Config{})
+	ctx := context.Background()
+
+	t.Run("JSON Body And Query", func(t *testing.T) {
+		res, err := httputil.Do[createOrderRequest, createOrderResponse](ctx, client, httputil.Request[createOrderRequest]{
+			Method: http.MethodPost,
+			URL:    mockServer.URL + "/orders?source=app",
+			Query: struct {
+				Page int `query:"page"`
+			}{Page: 2},
+			Body: createOrderRequest{Amount: 100},
+		})
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if res.Id != "order-1" || res.ContentType != "application/json" || res.Body != `{"amount":100}` || res.Query != "page=2&source=app" {
+			t.Errorf("Do() response = %+v", res)
+		}
+	})
+
+	t.Run("Form Body", func(t *testing.T) {
+		res, err := httputil.Do[createOrderRequest, createOrderResponse](ctx, client, httputil.Request[createOrderRequest]{
+			Method:   http.MethodPost,
+			URL:      mockServer.URL + "/orders",
+			Body:     createOrderRequest{Amount: 100, Note: "a b"},
+			Encoding: httputil.EncodingForm,
+		})
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if res.ContentType != "application/x-www-form-urlencoded" || res.Body != "amount=100&note=a+b" {
+			t.Errorf("Do() response = %+v", res)
+		}
+	})
+
+	t.Run("Decoded Error Body", func(t *testing.T) {
+		_, err := httputil.DoWithError[createOrderRequest, createOrderResponse, upstreamError](ctx, client, httputil.Request[createOrderRequest]{
+			Method: http.MethodPost,
+			URL:    mockServer.URL + "/fail",
+			Body:   createOrderRequest{Amount: 1},
+		})
+		apiErr, ok := err.(*httputil.APIError[upstreamError])
+		if !ok {
+			t.Fatalf("DoWithError() error = %v, want *APIError", err)
+		}
+		if apiErr.StatusCode != http.StatusBadRequest || apiErr.Body.Message != "amount too low" {
+			t.Errorf("DoWithError() error = %+v", apiErr)
+		}
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		written, err := httputil.Download(ctx, client, mockServer.URL+"/files/1", nil, buf)
+		if err != nil || written != int64(buf.Len()) || written == 0 {
+			t.Errorf("Download() written = %v, err = %v", written, err)
+		}
+	})
+}
+
+func TestDownloadSlowBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		headerDelay time.Duration
+		wantErr     bool
+	}{
+		{name: "Body Outlasting The Timeout Completes"},
+		{name: "Headers Past The Timeout Fail", headerDelay: 300 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.headerDelay)
+				w.WriteHeader(http.StatusOK)
+
+				// 5 chunks 60ms apart, the whole body takes 3 times the client timeout
+				for i := 0; i < 5; i++ {
+					w.Write([]byte("chunk"))
+					w.(http.Flusher).Flush()
+					time.Sleep(60 * time.Millisecond)
+				}
+			}))
+			defer mockServer.Close()
+
+			client := httputil.New(httputil.Config{Timeout: 100 * time.Millisecond})
+
+			buf := &bytes.Buffer{}
+			written, err := httputil.Download(context.Background(), client, mockServer.URL, nil, buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Download() error = %v, want context.DeadlineExceeded", err)
+				}
+				return
+			}
+
+			if written != 25 || buf.String() != strings.Repeat("chunk", 5) {
+				t.Errorf("Download() = %d bytes %q", written, buf.String())
+			}
+		})
+	}
+}
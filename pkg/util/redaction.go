@@ -0,0 +1,510 @@
+package util
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces values that are hidden entirely
+const Redacted = "[REDACTED]"
+
+// Mask kinds of RedactionPattern.Mask and of the `mask` struct tag
+//
+// e.g.
+//
+//	type ChargeRequest struct {
+//		CardNumber string `json:"card_number" mask:"card"`
+//		Email      string `json:"email" mask:"email"`
+//		Cvv        string `json:"cvv" mask:"full"`
+//	}
+const (
+	MaskFull  = "full"
+	MaskCard  = "card"
+	MaskEmail = "email"
+	MaskPhone = "phone"
+)
+
+// maxRedactionDepth stops the walk on deeply nested or cyclic values
+const maxRedactionDepth = 32
+
+type RedactionPattern struct {
+	Name  string
+	Regex string
+	// Mask is one of the Mask kinds, a MaskCard match is only redacted when it passes the Luhn check
+	Mask string
+}
+
+type RedactionConfig struct {
+	// Headers are redacted entirely, names are case-insensitive
+	Headers []string
+	// Fields are JSON keys and struct fields redacted at any depth, case-insensitive
+	Fields []string
+	// JSONPaths are dot separated paths from the root, "*" matches any key or array index
+	JSONPaths []string
+	// Patterns are searched in every string value
+	Patterns []RedactionPattern
+}
+
+// DefaultRedactionConfig covers credentials, card data, emails and phone numbers
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+			"X-Api-Key", "X-Signature",
+		},
+		Fields: []string{
+			"password", "secret", "token", "access_token", "refresh_token",
+			"card_number", "cvv", "cvc", "pin",
+		},
+		Patterns: []RedactionPattern{
+			{Name: "card", Regex: `\b\d(?:[ -]?\d){12,18}\b`, Mask: MaskCard},
+			{Name: "email", Regex: `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`, Mask: MaskEmail},
+			{Name: "phone", Regex: `(?:\+\d{1,3}[ -]?|\b0)\d{2,4}[ -]?\d{3,4}[ -]?\d{3,5}\b`, Mask: MaskPhone},
+		},
+	}
+}
+
+type redactionPattern struct {
+	regex *regexp.Regexp
+	mask  string
+}
+
+// Redactor hides sensitive values in headers, JSON and form bodies, strings and Go values.
+//
+// A nil *Redactor returns every input unchanged.
+type Redactor struct {
+	headers  map[string]bool
+	fields   map[string]bool
+	paths    [][]string
+	patterns []redactionPattern
+	// fieldPairs finds "field": value pairs of Fields in text that isn't valid JSON, such as a
+	// truncated body
+	fieldPairs *regexp.Regexp
+}
+
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	r := &Redactor{
+		headers: make(map[string]bool, len(config.Headers)),
+		fields:  make(map[string]bool, len(config.Fields)),
+	}
+
+	for _, header := range config.Headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	quotedFields := make([]string, 0, len(config.Fields))
+	for _, field := range config.Fields {
+		r.fields[strings.ToLower(field)] = true
+		quotedFields = append(quotedFields, regexp.QuoteMeta(field))
+	}
+	if len(quotedFields) > 0 {
+		r.fieldPairs = regexp.MustCompile(`(?i)"(` + strings.Join(quotedFields, "|") + `)"(\s*:\s*)(?:"(?:[^"\\]|\\.)*"?|-?[0-9.eE+-]+)`)
+	}
+
+	for _, path := range config.JSONPaths {
+		r.paths = append(r.paths, strings.Split(path, "."))
+	}
+
+	for _, pattern := range config.Patterns {
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern.Name, err)
+		}
+		r.patterns = append(r.patterns, redactionPattern{regex, pattern.Mask})
+	}
+
+	return r, nil
+}
+
+// HeaderNames returns the canonical names of the redacted headers
+func (r *Redactor) HeaderNames() []string {
+	if r == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(r.headers))
+	for name := range r.headers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// FieldNames returns the lower-cased redacted field names
+func (r *Redactor) FieldNames() []string {
+	if r == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(r.fields))
+	for name := range r.fields {
+		names = append(names, name)
+	}
+	return names
+}
+
+// IsField reports whether values under key are always redacted
+func (r *Redactor) IsField(key string) bool {
+	return r != nil && r.fields[strings.ToLower(key)]
+}
+
+// Headers returns a copy of header with redacted values
+func (r *Redactor) Headers(header http.Header) http.Header {
+	if r == nil {
+		return header
+	}
+
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			redacted[name] = []string{Redacted}
+			continue
+		}
+
+		copied := make([]string, len(values))
+		for i, value := range values {
+			copied[i] = r.String(value)
+		}
+		redacted[name] = copied
+	}
+	return redacted
+}
+
+// String masks every pattern match in s
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	for _, pattern := range r.patterns {
+		s = pattern.regex.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern.mask == MaskCard && !isLuhnValid(match) {
+				return match
+			}
+			return Mask(pattern.mask, match)
+		})
+	}
+	return s
+}
+
+// JSON redacts fields, paths and patterns in a JSON document. Anything that isn't valid JSON
+// only gets fields and patterns redacted. The document is only re-encoded when something was redacted.
+func (r *Redactor) JSON(body []byte) []byte {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil || decoder.More() {
+		text := string(body)
+		if r.fieldPairs != nil {
+			text = r.fieldPairs.ReplaceAllString(text, `"$1"${2}"`+Redacted+`"`)
+		}
+		return []byte(r.String(text))
+	}
+
+	redacted, changed := r.redact(reflect.ValueOf(document), nil, 0)
+	if !changed {
+		return body
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redacted); err != nil {
+		return []byte(Redacted)
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n")
+}
+
+// Form redacts fields and patterns in an application/x-www-form-urlencoded body, keeping the
+// pairs in order. Redacted and masked values are written unescaped, as they are only logged.
+func (r *Redactor) Form(body []byte) []byte {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+
+	pairs := strings.Split(string(body), "&")
+	changed := false
+	for i, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		if r.fields[strings.ToLower(unescapeQuery(key))] {
+			pairs[i] = key + "=" + Redacted
+			changed = true
+			continue
+		}
+
+		unescaped := unescapeQuery(value)
+		if masked := r.String(unescaped); masked != unescaped {
+			pairs[i] = key + "=" + masked
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+	return []byte(strings.Join(pairs, "&"))
+}
+
+// unescapeQuery decodes s, a cut escape at the end of a truncated body leaves s as is
+func unescapeQuery(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// Value redacts v, honouring `mask` struct tags. v is returned as is when nothing was redacted,
+// otherwise structs, maps and slices on the way to a redacted value are copied into
+// map[string]interface{} and []interface{}.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r == nil || v == nil {
+		return v
+	}
+
+	redacted, changed := r.redact(reflect.ValueOf(v), nil, 0)
+	if !changed {
+		return v
+	}
+	return redacted
+}
+
+func (r *Redactor) redact(v reflect.Value, path []string, depth int) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+
+	if depth > maxRedactionDepth {
+		return v.Interface(), false
+	}
+
+	// Types with their own encoding are opaque, except strings hiding their value behind String()
+	if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface && v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case json.Number:
+			return value, false
+		case json.Marshaler, encoding.TextMarshaler:
+			return value, false
+		case error:
+			redacted := r.String(value.Error())
+			return redacted, redacted != value.Error()
+		case fmt.Stringer:
+			if v.Kind() == reflect.String {
+				redacted := r.String(value.String())
+				return redacted, redacted != v.String()
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return r.redact(v.Elem(), path, depth+1)
+
+	case reflect.String:
+		redacted := r.String(v.String())
+		return redacted, redacted != v.String()
+
+	case reflect.Map:
+		copied := make(map[string]interface{}, v.Len())
+		changed := false
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			value, redacted := r.redactChild(iter.Value(), append(path, key), key, depth)
+			copied[key] = value
+			changed = changed || redacted
+		}
+		return copied, changed
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), false
+		}
+
+		copied := make([]interface{}, v.Len())
+		changed := false
+		for i := 0; i < v.Len(); i++ {
+			value, redacted := r.redactChild(v.Index(i), append(path, strconv.Itoa(i)), "", depth)
+			copied[i] = value
+			changed = changed || redacted
+		}
+		return copied, changed
+
+	case reflect.Struct:
+		copied := map[string]interface{}{}
+		changed := r.redactStruct(v, path, depth, copied)
+		return copied, changed
+	}
+
+	if v.CanInterface() {
+		return v.Interface(), false
+	}
+	return nil, false
+}
+
+// redactChild redacts an element of a map, slice or struct found under key
+func (r *Redactor) redactChild(v reflect.Value, path []string, key string, depth int) (interface{}, bool) {
+	if (key != "" && r.fields[strings.ToLower(key)]) || r.matchPath(path) {
+		if isEmptyValue(v) {
+			return interfaceOf(v), false
+		}
+		return Redacted, true
+	}
+
+	return r.redact(v, path, depth+1)
+}
+
+// redactStruct adds the exported fields of v to copied under their JSON name, embedded
+// structs without a JSON name are flattened like encoding/json does
+func (r *Redactor) redactStruct(v reflect.Value, path []string, depth int, copied map[string]interface{}) bool {
+	changed := false
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			embedded := reflect.Indirect(value)
+			if embedded.IsValid() && embedded.Kind() == reflect.Struct {
+				changed = r.redactStruct(embedded, path, depth+1, copied) || changed
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if mask, ok := field.Tag.Lookup("mask"); ok {
+			if isEmptyValue(value) {
+				copied[name] = interfaceOf(value)
+				continue
+			}
+			copied[name] = Mask(mask, fmt.Sprint(reflect.Indirect(value).Interface()))
+			changed = true
+			continue
+		}
+
+		redacted, fieldChanged := r.redactChild(value, append(path, name), name, depth)
+		copied[name] = redacted
+		changed = changed || fieldChanged
+	}
+	return changed
+}
+
+func (r *Redactor) matchPath(path []string) bool {
+	for _, pattern := range r.paths {
+		if len(pattern) != len(path) {
+			continue
+		}
+
+		matched := true
+		for i, segment := range pattern {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask hides value according to mask, unknown masks hide it entirely
+func Mask(mask, value string) string {
+	switch mask {
+	case MaskCard:
+		digits := onlyDigits(value)
+		if len(digits) < 10 {
+			return Redacted
+		}
+		return MaskCreditCardNumber(digits)
+
+	case MaskEmail:
+		local, domain, found := strings.Cut(value, "@")
+		if !found || local == "" {
+			return Redacted
+		}
+		return local[:1] + "***@" + domain
+
+	case MaskPhone:
+		digits := onlyDigits(value)
+		if len(digits) <= 4 {
+			return Redacted
+		}
+		return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+	}
+
+	return Redacted
+}
+
+func onlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// isLuhnValid tells card numbers apart from other long digit runs such as ids and timestamps
+func isLuhnValid(value string) bool {
+	digits := onlyDigits(value)
+	if len(digits) < 13 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	return !v.IsValid() || v.IsZero()
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,168 @@
+package util_test
+
+import (
+	"boilerplate-service/pkg/util"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func newTestRedactor(t *testing.T) *util.Redactor {
+	config := util.DefaultRedactionConfig()
+	config.JSONPaths = []string{"customer.*.id_number"}
+
+	redactor, err := util.NewRedactor(config)
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	return redactor
+}
+
+func TestRedactorJSON(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "Field At Any Depth",
+			body: `{"user":{"name":"budi","password":"hunter2"}}`,
+			want: `{"user":{"name":"budi","password":"[REDACTED]"}}`,
+		},
+		{
+			name: "JSON Path With Wildcard",
+			body: `{"customer":{"primary":{"id_number":"3171"}}}`,
+			want: `{"customer":{"primary":{"id_number":"[REDACTED]"}}}`,
+		},
+		{
+			name: "Card Number In String",
+			body: `{"note":"paid with 4111 1111 1111 1111"}`,
+			want: `{"note":"paid with 411111******1111"}`,
+		},
+		{
+			name: "Digits Failing Luhn Are Kept",
+			body: `{"order_id":"1234567890123456"}`,
+			want: `{"order_id":"1234567890123456"}`,
+		},
+		{
+			name: "Unchanged Document Is Not Re-encoded",
+			body: `{ "b": 1, "a": 2.50 }`,
+			want: `{ "b": 1, "a": 2.50 }`,
+		},
+		{
+			name: "Invalid JSON Is Redacted As Text",
+			body: `email=budi@example.com`,
+			want: `email=b***@example.com`,
+		},
+		{
+			name: "Fields In Truncated JSON",
+			body: `{"Pin": 1234, "user":{"password":"hunt`,
+			want: `{"Pin": "[REDACTED]", "user":{"password":"[REDACTED]"`,
+		},
+		{
+			name: "Escaped Quote In Truncated JSON",
+			body: `{"token":"a\"b","note":"budi@example.com`,
+			want: `{"token":"[REDACTED]","note":"b***@example.com`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactor.JSON([]byte(tt.body))); got != tt.want {
+				t.Errorf("JSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorForm(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "Login Form",
+			body: `username=budi&password=hunter2&remember=1`,
+			want: `username=budi&password=[REDACTED]&remember=1`,
+		},
+		{
+			name: "Escaped Field Name",
+			body: `user%5Bname%5D=budi&Access%5Ftoken=abc%26def`,
+			want: `user%5Bname%5D=budi&Access%5Ftoken=[REDACTED]`,
+		},
+		{
+			name: "Pattern In Escaped Value",
+			body: `note=paid+with+4111+1111+1111+1111&email=budi%40example.com`,
+			want: `note=paid with 411111******1111&email=b***@example.com`,
+		},
+		{
+			name: "Unchanged Form Is Kept As Is",
+			body: `a=1&b=two+words&flag`,
+			want: `a=1&b=two+words&flag`,
+		},
+		{
+			name: "Truncated Form",
+			body: `name=budi&token=abc%2`,
+			want: `name=budi&token=[REDACTED]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactor.Form([]byte(tt.body))); got != tt.want {
+				t.Errorf("Form() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorValue(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	type charge struct {
+		Amount     int    `json:"amount"`
+		CardNumber string `json:"pan" mask:"card"`
+		Phone      string `json:"phone" mask:"phone"`
+		Token      string
+	}
+
+	got := redactor.Value(&charge{Amount: 10, CardNumber: "4111111111111111", Phone: "+628123456789", Token: "abc"})
+	want := map[string]interface{}{
+		"amount": 10,
+		"pan":    "411111******1111",
+		"phone":  "********6789",
+		"Token":  util.Redacted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Value() = %#v, want %#v", got, want)
+	}
+
+	plain := struct{ Name string }{"budi"}
+	if got := redactor.Value(plain); got != plain {
+		t.Errorf("Value() = %#v, want the value unchanged", got)
+	}
+}
+
+func TestRedactorHeaders(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Content-Type", "application/json")
+
+	got := redactor.Headers(header)
+	if got.Get("Authorization") != util.Redacted {
+		t.Errorf("Authorization = %q, want %q", got.Get("Authorization"), util.Redacted)
+	}
+	if got.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, want it unchanged", got.Get("Content-Type"))
+	}
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Headers() modified its input")
+	}
+}
@@ -10,14 +10,21 @@ const (
 	HttpStatusErrorUnauthorized    string = "41"
 	HttpStatusErrorNotFound        string = "44"
 	HttpStatusErrorDuplicatedCheck string = "49"
+	HttpStatusErrorUnprocessable   string = "42"
+	// 29 for 429: 49 is already the 409 duplicate check and 4X codes stay with the 40X statuses
+	HttpStatusErrorRateLimited string = "29"
+	HttpStatusErrorForbidden   string = "43"
 )
 
 const (
-	HttpErrInternal     string = "ERROR_INTERNAL"
-	HttpErrDatabase     string = "ERROR_DATABASE"
-	HttpErrThirdParty   string = "ERROR_THIRD_PARTY"
-	HttpErrRequest      string = "ERROR_REQUEST"
-	HttpErrUnauthorized string = "ERROR_UNAUTHORIZED"
-	HttpErrNotFound     string = "ERROR_NOT_FOUND"
-	HttpErrDupCheck     string = "ERROR_DUPLICATE_CHECK"
+	HttpErrInternal      string = "ERROR_INTERNAL"
+	HttpErrDatabase      string = "ERROR_DATABASE"
+	HttpErrThirdParty    string = "ERROR_THIRD_PARTY"
+	HttpErrRequest       string = "ERROR_REQUEST"
+	HttpErrUnauthorized  string = "ERROR_UNAUTHORIZED"
+	HttpErrNotFound      string = "ERROR_NOT_FOUND"
+	HttpErrDupCheck      string = "ERROR_DUPLICATE_CHECK"
+	HttpErrUnprocessable string = "ERROR_UNPROCESSABLE"
+	HttpErrRateLimited   string = "ERROR_RATE_LIMITED"
+	HttpErrForbidden     string = "ERROR_FORBIDDEN"
 )
@@ -10,8 +10,14 @@ func HttpStatusErrorCode(errType string) (string, int) {
 		return HttpStatusErrorNotFound, http.StatusNotFound
 	case HttpErrUnauthorized:
 		return HttpStatusErrorUnauthorized, http.StatusUnauthorized
+	case HttpErrForbidden:
+		return HttpStatusErrorForbidden, http.StatusForbidden
 	case HttpErrDupCheck:
 		return HttpStatusErrorDuplicatedCheck, http.StatusConflict
+	case HttpErrUnprocessable:
+		return HttpStatusErrorUnprocessable, http.StatusUnprocessableEntity
+	case HttpErrRateLimited:
+		return HttpStatusErrorRateLimited, http.StatusTooManyRequests
 	case HttpErrRequest:
 		return HttpStatusErrorRequest, http.StatusBadRequest
 	default:
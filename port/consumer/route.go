@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"boilerplate-service/config"
+	"boilerplate-service/pkg/rabbitMQExt"
+)
+
+// Route binds a queue consumer config to the handler processing its messages
+type Route struct {
+	Config  rabbitMQExt.ConsumerConfig
+	Handler rabbitMQExt.Handler
+}
+
+func ConsumerRoute(
+	config *config.Config,
+) []Route {
+	routes := []Route{
+		// Register queue handlers here, e.g.
+		// {
+		// 	Config: rabbitMQExt.ConsumerConfig{
+		// 		Queue:              "boilerplate.order.created",
+		// 		Exchange:           "order",
+		// 		RoutingKeys:        []string{"order.created"},
+		// 		DeadLetterExchange: "boilerplate.dlx",
+		// 		RetryDelay:         5 * time.Second,
+		// 	},
+		// 	Handler: inbox.Dedupe(inboxStore, "boilerplate.order.created", logger, orderCreatedConsumer.Handle),
+		// },
+	}
+
+	for i := range routes {
+		applyConcurrency(config, &routes[i].Config)
+	}
+
+	return routes
+}
+
+// applyConcurrency sets prefetch and concurrency from the RABBITMQ.CONSUMERS entry of the queue,
+// falling back to RABBITMQ.CONCURRENCY / RABBITMQ.PREFETCH
+func applyConcurrency(config *config.Config, consumerConfig *rabbitMQExt.ConsumerConfig) {
+	if consumerConfig.Concurrency == 0 {
+		consumerConfig.Concurrency = config.RabbitMQConfig.Concurrency
+	}
+
+	if consumerConfig.Prefetch == 0 {
+		consumerConfig.Prefetch = config.RabbitMQConfig.Prefetch
+	}
+
+	for _, override := range config.RabbitMQConfig.Consumers {
+		if override.Queue != consumerConfig.Queue {
+			continue
+		}
+
+		if override.Concurrency != 0 {
+			consumerConfig.Concurrency = override.Concurrency
+		}
+
+		if override.Prefetch != 0 {
+			consumerConfig.Prefetch = override.Prefetch
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"boilerplate-service/pkg/jwtExt"
+	"boilerplate-service/port/http/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireScopesAndRoles(t *testing.T) {
+	principal := &jwtExt.Principal{Subject: "user-1", Scopes: []string{"orders:read"}, Roles: []string{"support"}}
+
+	tests := []struct {
+		name      string
+		guard     func(next http.Handler) http.Handler
+		principal *jwtExt.Principal
+		wantCode  int
+	}{
+		{name: "Scope Granted", guard: middleware.RequireScopes("orders:read"), principal: principal, wantCode: http.StatusOK},
+		{name: "Scope Missing", guard: middleware.RequireScopes("orders:read", "orders:write"), principal: principal, wantCode: http.StatusForbidden},
+		{name: "Scopes Without Principal", guard: middleware.RequireScopes("orders:read"), wantCode: http.StatusUnauthorized},
+		{name: "Role Held", guard: middleware.RequireRoles("admin", "support"), principal: principal, wantCode: http.StatusOK},
+		{name: "Role Missing", guard: middleware.RequireRoles("admin"), principal: principal, wantCode: http.StatusForbidden},
+		{name: "Roles Without Principal", guard: middleware.RequireRoles("admin"), wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.principal != nil {
+				req = req.WithContext(jwtExt.WithPrincipal(req.Context(), tt.principal))
+			}
+
+			res := httptest.NewRecorder()
+			tt.guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(res, req)
+
+			if res.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", res.Code, tt.wantCode)
+			}
+		})
+	}
+}
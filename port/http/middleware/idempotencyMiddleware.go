@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/redisExt"
+	"boilerplate-service/pkg/util/response"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	idempotencyKeyMaxLength   = 255
+	idempotencyRecordTTL      = 24 * time.Hour
+	idempotencyLockExpiry     = 2 * time.Minute
+	idempotencyKeyPrefix      = "idempotency:"
+	idempotencyLockKeyPrefix  = "idempotency-lock:"
+	idempotencySkippedHeaders = "X-Request-Id"
+)
+
+var (
+	errIdempotencyKeyTooLong  = errors.New("idempotency key must be at most 255 characters")
+	errIdempotencyInProgress  = errors.New("a request with this idempotency key is still in progress")
+	errIdempotencyKeyMismatch = errors.New("idempotency key was already used with a different request payload")
+	errIdempotencyUnavailable = errors.New("idempotency store is unavailable")
+)
+
+// idempotencyRecord is the value stored in redis for one idempotency key.
+// A record without Completed marks a request that is still being handled.
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// idempotencyWriter captures the response so it can be stored for replay
+type idempotencyWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// IdempotencyMiddleware makes POST, PUT and PATCH requests carrying an Idempotency-Key header safe to retry.
+// The first request runs under a redis lock and its response is stored for 24 hours, retries with the same
+// payload get the stored response back, 409 while the first request is still running and 422 when the
+// payload differs. Server errors are not stored so the request can be retried.
+//
+// Keys are scoped to the caller so one client can't replay another's response: mount it after
+// AuthMiddleware or SignatureVerifier, anonymous requests are scoped to the client IP.
+func IdempotencyMiddleware(redisClient redisExt.IRedisExt, logger logger.ILogger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(HeaderIdempotencyKey)
+			if idempotencyKey == "" || !isIdempotentMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > idempotencyKeyMaxLength {
+				response.SendResponseError(w, response.HttpErrRequest, errIdempotencyKeyTooLong)
+				return
+			}
+
+			ctx := r.Context()
+
+			reqBody, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.SendResponseError(w, response.HttpErrRequest, err)
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+
+			fingerprint := idempotencyFingerprint(r, reqBody)
+			scope := idempotencyCaller(r) + ":" + r.Method + ":" + r.URL.Path + ":" + idempotencyKey
+			recordKey := idempotencyKeyPrefix + scope
+
+			mutex := redisClient.NewMutex(
+				idempotencyLockKeyPrefix+scope,
+				redsync.WithExpiry(idempotencyLockExpiry),
+				redsync.WithTries(1),
+			)
+			if err := mutex.LockContext(ctx); err != nil {
+				// Someone else holds the key, tell apart a retry from a reuse with another payload.
+				// The lookup also surfaces redis being down, which redsync reports as a taken lock.
+				record, getErr := getIdempotencyRecord(ctx, redisClient, recordKey)
+				if getErr != nil {
+					logger.Error(ctx, "Idempotency lock failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err), zap.NamedError("lookup_error", getErr))
+					response.SendResponseError(w, response.HttpErrInternal, errIdempotencyUnavailable)
+					return
+				}
+
+				if record != nil && record.Fingerprint != fingerprint {
+					response.SendResponseError(w, response.HttpErrUnprocessable, errIdempotencyKeyMismatch)
+					return
+				}
+
+				response.SendResponseError(w, response.HttpErrDupCheck, errIdempotencyInProgress)
+				return
+			}
+			defer func() {
+				if _, err := mutex.UnlockContext(context.WithoutCancel(ctx)); err != nil {
+					logger.Warn(ctx, "Idempotency unlock failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err))
+				}
+			}()
+
+			record, err := getIdempotencyRecord(ctx, redisClient, recordKey)
+			if err != nil {
+				logger.Error(ctx, "Idempotency record lookup failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err))
+				response.SendResponseError(w, response.HttpErrInternal, errIdempotencyUnavailable)
+				return
+			}
+
+			if record != nil {
+				if record.Fingerprint != fingerprint {
+					response.SendResponseError(w, response.HttpErrUnprocessable, errIdempotencyKeyMismatch)
+					return
+				}
+
+				if record.Completed {
+					replayIdempotentResponse(w, record)
+					return
+				}
+			}
+
+			// Mark the key as processing so concurrent retries can be checked against the fingerprint
+			processing := &idempotencyRecord{Fingerprint: fingerprint}
+			if err := setIdempotencyRecord(ctx, redisClient, recordKey, processing, idempotencyLockExpiry); err != nil {
+				logger.Error(ctx, "Idempotency record store failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err))
+				response.SendResponseError(w, response.HttpErrInternal, errIdempotencyUnavailable)
+				return
+			}
+
+			writer := &idempotencyWriter{ResponseWriter: w}
+			next.ServeHTTP(writer, r)
+
+			storeCtx := context.WithoutCancel(ctx)
+			if writer.status == 0 || writer.status >= http.StatusInternalServerError {
+				if err := redisClient.Del(storeCtx, recordKey).Err(); err != nil {
+					logger.Warn(ctx, "Idempotency record delete failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err))
+				}
+				return
+			}
+
+			completed := &idempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      writer.status,
+				Header:      w.Header().Clone(),
+				Body:        writer.body.Bytes(),
+			}
+			completed.Header.Del(idempotencySkippedHeaders)
+			if err := setIdempotencyRecord(storeCtx, redisClient, recordKey, completed, idempotencyRecordTTL); err != nil {
+				logger.Error(ctx, "Idempotency response store failed", zap.String("idempotency_key", idempotencyKey), zap.Error(err))
+			}
+		})
+	}
+}
+
+func idempotencyCaller(r *http.Request) string {
+	if subject := authenticatedSubject(r); subject != "" {
+		return "sub:" + subject
+	}
+
+	return RateLimitByIP(r)
+}
+
+func isIdempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// idempotencyFingerprint hashes what identifies a request so a key can't be reused for another payload
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.RequestURI()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func getIdempotencyRecord(ctx context.Context, redisClient redisExt.IRedisExt, key string) (*idempotencyRecord, error) {
+	raw, err := redisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := &idempotencyRecord{}
+	if err := json.Unmarshal(raw, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func setIdempotencyRecord(ctx context.Context, redisClient redisExt.IRedisExt, key string, record *idempotencyRecord, ttl time.Duration) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return redisClient.Set(ctx, key, raw, ttl).Err()
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, record *idempotencyRecord) {
+	for key, values := range record.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
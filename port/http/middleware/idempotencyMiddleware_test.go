@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/port/http/middleware"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type nopLogger struct {
+	logger.ILogger
+}
+
+func (nopLogger) Debug(ctx context.Context, msg string, fields ...zap.Field) {}
+func (nopLogger) Info(ctx context.Context, msg string, fields ...zap.Field)  {}
+func (nopLogger) Error(ctx context.Context, msg string, fields ...zap.Field) {}
+func (nopLogger) Warn(ctx context.Context, msg string, fields ...zap.Field)  {}
+
+// withSubject stands in for AuthMiddleware, authenticating every request as subject
+func withSubject(subject string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), constant.CtxSubjectKey, subject)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func TestIdempotencyMiddlewareScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		subjects     []string
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{name: "Retry By The Same Subject Is Replayed", subjects: []string{"alice", "alice"}, wantCalls: 1, wantReplayed: true},
+		{name: "Same Key From Another Subject Runs Again", subjects: []string{"alice", "bob"}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := middleware.IdempotencyMiddleware(newFakeRedis(), nopLogger{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id":1}`))
+			}))
+
+			var res *httptest.ResponseRecorder
+			for _, subject := range tt.subjects {
+				req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"amount":10}`))
+				req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+
+				res = httptest.NewRecorder()
+				withSubject(subject, handler).ServeHTTP(res, req)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if replayed := res.Header().Get(middleware.HeaderIdempotentReplayed) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if res.Code != http.StatusCreated || res.Body.String() != `{"id":1}` {
+				t.Errorf("response = %d %s", res.Code, res.Body.String())
+			}
+		})
+	}
+}
@@ -1,39 +1,129 @@
 package middleware
 
 import (
-	"boilerplate-service/constant"
 	"boilerplate-service/pkg/logger"
 	"boilerplate-service/pkg/newRelicExt"
+	"boilerplate-service/pkg/traceContext"
+	"boilerplate-service/pkg/util"
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
-	"regexp"
+	"path"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/go-chi/chi/middleware"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"go.uber.org/zap"
 )
 
-// ResponseWriter is a wrapper around http.ResponseWriter that captures the response body
+const defaultMaxLoggedBodySize = 16 << 10
+
+// defaultLoggedContentTypes are the media types whose bodies are logged when
+// LoggerConfig.ContentTypes is empty, any "+json" type is logged as well
+var defaultLoggedContentTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"application/xml",
+	"text/plain",
+	"text/xml",
+}
+
+type LoggerConfig struct {
+	// MaxRequestBodySize and MaxResponseBodySize cap the logged bytes, the rest is cut with a marker
+	MaxRequestBodySize  int
+	MaxResponseBodySize int
+	// ContentTypes are the media types whose bodies are logged, others are logged as a marker
+	ContentTypes []string
+	// SkipBodyPaths are path.Match patterns of routes whose bodies are never logged,
+	// e.g. "/api/v1/files/*"
+	SkipBodyPaths []string
+
+	Redactor *util.Redactor
+}
+
+// ResponseWriter is a wrapper around http.ResponseWriter that captures the start of the response body.
+// It keeps http.Flusher and http.Hijacker working for streaming and websocket handlers.
 type ResponseWriter struct {
 	http.ResponseWriter
-	body   *bytes.Buffer
 	Status int
+	// Size is the number of body bytes written
+	Size int
+
+	body        *bytes.Buffer
+	maxBodySize int
+	capture     func(contentType string) bool
+	capturing   bool
+	wroteHeader bool
 }
 
-func (w ResponseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
-	return w.ResponseWriter.Write(b)
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		// Same sniffing as net/http so the content type check sees the real type
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.capturing {
+		if room := w.maxBodySize - w.body.Len(); room > 0 {
+			w.body.Write(b[:min(len(b), room)])
+		}
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.Size += n
+	return n, err
 }
 
-// WriteHeader overrides the default WriteHeader method to capture the response status
-func (rw *ResponseWriter) WriteHeader(statusCode int) {
-	rw.Status = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
+// WriteHeader overrides the default WriteHeader method to capture the response status.
+// Informational 1xx statuses are passed through, the final status is still to come.
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if statusCode >= 100 && statusCode <= 199 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.wroteHeader = true
+	w.Status = statusCode
+	w.capturing = w.body != nil && w.capture(w.Header().Get("Content-Type"))
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *ResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not implement http.Hijacker", w.ResponseWriter)
+	}
+
+	w.capturing = false
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the original writer
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func getDurationInMilliseconds(start time.Time) float64 {
@@ -44,7 +134,19 @@ func getDurationInMilliseconds(start time.Time) float64 {
 	return rounded
 }
 
-func LoggerMiddleware(app newRelicExt.INewRelicExt, logger logger.ILogger) func(next http.Handler) http.Handler {
+// LoggerMiddleware logs every request and response, with headers and bodies passed through
+// LoggerConfig.Redactor. Only the first bytes of bodies with a logged content type are kept.
+func LoggerMiddleware(app newRelicExt.INewRelicExt, logger logger.ILogger, config LoggerConfig) func(next http.Handler) http.Handler {
+	if config.MaxRequestBodySize == 0 {
+		config.MaxRequestBodySize = defaultMaxLoggedBodySize
+	}
+	if config.MaxResponseBodySize == 0 {
+		config.MaxResponseBodySize = defaultMaxLoggedBodySize
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = defaultLoggedContentTypes
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			txn := app.App().StartTransaction("HTTP Request")
@@ -55,61 +157,178 @@ func LoggerMiddleware(app newRelicExt.INewRelicExt, logger logger.ILogger) func(
 			segment := txn.StartSegment(segmentName)
 			defer segment.End()
 
+			// Accepts incoming traceparent and newrelic distributed tracing headers
+			txn.SetWebRequestHTTP(r)
+
 			// Add the transaction to the context
 			r = newrelic.RequestWithTransactionContext(r, txn)
 
-			// Get or Set X-Request-Id
-			requestId := r.Header.Get("X-Request-Id")
-			if requestId == "" {
-				requestId = uuid.New().String()
+			// TraceMiddleware normally runs first, the trace ids follow New Relic's once it has a transaction
+			trace, ok := traceContext.FromContext(r.Context())
+			if !ok {
+				trace, _ = traceContext.Extract(r.Header)
 			}
-			ctx := context.WithValue(r.Context(), "trace_id", requestId)
-			r = r.WithContext(ctx)
+			trace = trace.WithNewRelic(txn)
 
-			w.Header().Set("X-Request-Id", requestId)
+			// A request id generated by TraceMiddleware follows the New Relic trace id, only chi's
+			// access log, printed by an outer middleware, keeps the first one
+			w.Header().Set(traceContext.HeaderRequestId, trace.RequestId)
+			ctx := traceContext.NewContext(r.Context(), trace)
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, trace.RequestId)
+			r = r.WithContext(ctx)
 
 			// Start timer
 			start := time.Now()
-			reqBody, _ := io.ReadAll(r.Body)
-			r.Body.Close()
-			r.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+			logBody := !config.skipBody(r.URL.Path)
 
 			// Add Request Log
-			requestStringify := strings.ReplaceAll(string(reqBody), " ", "")
-			regexpNewLineAndTab := regexp.MustCompile(`\r?\n`)
-			requestStringify = regexpNewLineAndTab.ReplaceAllString(requestStringify, "")
+			requestPayload := ""
+			if logBody {
+				requestPayload = config.requestPayload(r)
+			}
 			requestLog := map[string]interface{}{
-				"request_id":      requestId,
+				"request_id":      trace.RequestId,
 				"request_url":     r.URL.Path,
 				"request_method":  r.Method,
-				"request_headers": r.Header,
-				"request_payload": requestStringify,
+				"request_headers": config.Redactor.Headers(r.Header),
+				"request_payload": requestPayload,
 			}
 			logger.Info(ctx, fmt.Sprintf("%s %s Request Log", r.Method, r.URL.Path), zap.Any("data", requestLog))
 
 			// Wrap the original writer with our custom writer
-			wrappedBody := &bytes.Buffer{}
-			wrappedWriter := &ResponseWriter{ResponseWriter: w, body: wrappedBody}
+			wrappedWriter := &ResponseWriter{
+				ResponseWriter: w,
+				Status:         http.StatusOK,
+				maxBodySize:    config.MaxResponseBodySize,
+				capture:        config.isLoggedContentType,
+			}
+			if logBody {
+				wrappedWriter.body = &bytes.Buffer{}
+			}
 
 			next.ServeHTTP(wrappedWriter, r)
 
 			// Stop timer
 			duration := getDurationInMilliseconds(start)
 
-			go func(requestId string, duration float64, wrappedWriter *ResponseWriter) {
-				ctx := context.WithValue(context.Background(), constant.CtxTraceIdKey, requestId)
-
-				// Add Response Log
-				responseStringify := strings.ReplaceAll(wrappedWriter.body.String(), " ", "")
-				regexpNewLineAndTab := regexp.MustCompile(`\r?\n`)
-				responseStringify = regexpNewLineAndTab.ReplaceAllString(responseStringify, "")
-				responseLog := map[string]interface{}{
-					"response_status": wrappedWriter.Status,
-					"response_data":   responseStringify,
-					"duration":        duration,
-				}
-				logger.Info(ctx, fmt.Sprintf("%s %s Response Log", r.Method, r.URL.Path), zap.Any("data", responseLog))
-			}(requestId, duration, wrappedWriter)
+			// Add Response Log
+			responseLog := map[string]interface{}{
+				"response_status": wrappedWriter.Status,
+				"response_data":   config.responsePayload(wrappedWriter),
+				"duration":        duration,
+			}
+			logger.Info(ctx, fmt.Sprintf("%s %s Response Log", r.Method, r.URL.Path), zap.Any("data", responseLog))
 		})
 	}
 }
+
+func (c LoggerConfig) skipBody(urlPath string) bool {
+	for _, pattern := range c.SkipBodyPaths {
+		if matched, _ := path.Match(pattern, urlPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoggedContentType reports whether bodies of contentType are logged, an empty type is
+// logged since it is usually a small error body
+func (c LoggerConfig) isLoggedContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+
+	for _, allowed := range c.ContentTypes {
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// requestPayload reads at most MaxRequestBodySize bytes of the body and puts them back in
+// front of the unread rest, so the handler still sees the whole body without it being buffered.
+func (c LoggerConfig) requestPayload(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if !c.isLoggedContentType(contentType) {
+		return omittedBody(contentType, r.ContentLength)
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r.Body, int64(c.MaxRequestBodySize)+1))
+	r.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(head), r.Body), Closer: r.Body}
+	if err != nil {
+		return ""
+	}
+
+	truncated := len(head) > c.MaxRequestBodySize
+	if truncated {
+		head = head[:c.MaxRequestBodySize]
+	}
+
+	return c.formatBody(contentType, head, truncated, r.ContentLength)
+}
+
+func (c LoggerConfig) responsePayload(w *ResponseWriter) string {
+	if w.Size == 0 {
+		return ""
+	}
+
+	if !w.capturing && w.body != nil {
+		return omittedBody(w.Header().Get("Content-Type"), int64(w.Size))
+	}
+
+	if w.body == nil {
+		return ""
+	}
+
+	return c.formatBody(w.Header().Get("Content-Type"), w.body.Bytes(), w.Size > w.body.Len(), int64(w.Size))
+}
+
+// formatBody redacts and compacts body, marking it when it was cut
+func (c LoggerConfig) formatBody(contentType string, body []byte, truncated bool, size int64) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		body = c.Redactor.Form(body)
+	} else {
+		body = c.Redactor.JSON(body)
+	}
+
+	compacted := &bytes.Buffer{}
+	if json.Compact(compacted, body) == nil {
+		body = compacted.Bytes()
+	}
+
+	if !truncated {
+		return string(body)
+	}
+
+	if size > 0 {
+		return fmt.Sprintf("%s...[truncated, %d bytes total]", body, size)
+	}
+	return fmt.Sprintf("%s...[truncated]", body)
+}
+
+func omittedBody(contentType string, size int64) string {
+	if size > 0 {
+		return fmt.Sprintf("[%s body not logged, %d bytes]", contentType, size)
+	}
+	return fmt.Sprintf("[%s body not logged]", contentType)
+}
+
+// prefixedBody replays the bytes read for the log before the rest of the original body
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,263 @@
+package middleware_test
+
+import (
+	"boilerplate-service/pkg/newRelicExt"
+	"boilerplate-service/pkg/util"
+	"boilerplate-service/port/http/middleware"
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.uber.org/zap"
+)
+
+type fakeNewRelic struct {
+	newRelicExt.INewRelicExt
+	app *newrelic.Application
+}
+
+func (f fakeNewRelic) App() *newrelic.Application { return f.app }
+
+func newFakeNewRelic(t *testing.T) fakeNewRelic {
+	t.Helper()
+
+	app, err := newrelic.NewApplication(newrelic.ConfigAppName("test"), newrelic.ConfigEnabled(false))
+	if err != nil {
+		t.Fatalf("NewApplication() error = %v", err)
+	}
+	return fakeNewRelic{app: app}
+}
+
+// recordingLogger keeps the "data" field of the request and response logs
+type recordingLogger struct {
+	nopLogger
+	logs []map[string]interface{}
+}
+
+func (l *recordingLogger) Info(ctx context.Context, msg string, fields ...zap.Field) {
+	for _, field := range fields {
+		if data, ok := field.Interface.(map[string]interface{}); ok && field.Key == "data" {
+			l.logs = append(l.logs, data)
+		}
+	}
+}
+
+// hijackableRecorder is a recorder that can be taken over like a websocket connection
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	deadline time.Time
+}
+
+func (r *hijackableRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadline = deadline
+	return nil
+}
+
+func (r *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	client, server := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	redactor, err := util.NewRedactor(util.DefaultRedactionConfig())
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		config       middleware.LoggerConfig
+		path         string
+		contentType  string
+		body         string
+		handler      http.HandlerFunc
+		wantRequest  string
+		wantResponse string
+		wantStatus   int
+	}{
+		{
+			name:        "JSON Is Compacted Keeping Spaces In Strings",
+			contentType: "application/json",
+			body:        "{ \"note\": \"two  words\",\n  \"password\": \"hunter2\" }",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{ "card": "4111 1111 1111 1111" }`))
+			},
+			wantRequest:  `{"note":"two  words","password":"[REDACTED]"}`,
+			wantResponse: `{"card":"411111******1111"}`,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:        "Form Login Is Redacted",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			body:        "username=budi&password=hunter2&token=abc",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+				w.Write([]byte("access_token=xyz&expires_in=3600"))
+			},
+			wantRequest:  "username=budi&password=[REDACTED]&token=[REDACTED]",
+			wantResponse: "access_token=[REDACTED]&expires_in=3600",
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:        "Bodies Past The Limit Are Truncated",
+			config:      middleware.LoggerConfig{MaxRequestBodySize: 5, MaxResponseBodySize: 4},
+			contentType: "text/plain",
+			body:        "0123456789",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("abcdefgh"))
+			},
+			wantRequest:  "01234...[truncated, 10 bytes total]",
+			wantResponse: "abcd...[truncated, 8 bytes total]",
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:        "Other Content Types Are Not Logged",
+			contentType: "image/png",
+			body:        "\x89PNG",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write([]byte{0, 1, 2})
+			},
+			wantRequest:  "[image/png body not logged, 4 bytes]",
+			wantResponse: "[application/octet-stream body not logged, 3 bytes]",
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:        "Skipped Paths Log No Body",
+			config:      middleware.LoggerConfig{SkipBodyPaths: []string{"/files/*"}},
+			path:        "/files/1",
+			contentType: "application/json",
+			body:        `{"name":"report"}`,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"id":1}`))
+			},
+			wantRequest:  "",
+			wantResponse: "",
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name: "Informational Status Is Not The Final Status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Link", "</style.css>; rel=preload")
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path == "" {
+				tt.path = "/orders"
+			}
+			tt.config.Redactor = redactor
+
+			handlerBody := ""
+			logger := &recordingLogger{}
+			handler := middleware.LoggerMiddleware(newFakeNewRelic(t), logger, tt.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				handlerBody = string(body)
+				tt.handler(w, r)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			// The handler reads the whole body however much of it was logged
+			if handlerBody != tt.body {
+				t.Errorf("handler body = %q, want %q", handlerBody, tt.body)
+			}
+			if len(logger.logs) != 2 {
+				t.Fatalf("logs = %v, want a request and a response log", logger.logs)
+			}
+
+			if got := logger.logs[0]["request_payload"]; got != tt.wantRequest {
+				t.Errorf("request_payload = %q, want %q", got, tt.wantRequest)
+			}
+			if got := logger.logs[1]["response_data"]; got != tt.wantResponse {
+				t.Errorf("response_data = %q, want %q", got, tt.wantResponse)
+			}
+			if got := logger.logs[1]["response_status"]; got != tt.wantStatus {
+				t.Errorf("response_status = %v, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLoggerMiddlewareResponseController(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(t *testing.T, w http.ResponseWriter)
+		check   func(t *testing.T, res *hijackableRecorder)
+	}{
+		{
+			name: "Flush",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				w.Write([]byte("data: 1\n\n"))
+				if err := http.NewResponseController(w).Flush(); err != nil {
+					t.Errorf("Flush() error = %v", err)
+				}
+			},
+			check: func(t *testing.T, res *hijackableRecorder) {
+				if !res.Flushed {
+					t.Error("the original writer wasn't flushed")
+				}
+			},
+		},
+		{
+			name: "Hijack",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				conn, _, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					t.Fatalf("Hijack() error = %v", err)
+				}
+				conn.Close()
+			},
+			check: func(t *testing.T, res *hijackableRecorder) {
+				if !res.hijacked {
+					t.Error("the original writer wasn't hijacked")
+				}
+			},
+		},
+		{
+			name: "Other Controls Reach The Original Writer",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				if err := http.NewResponseController(w).SetWriteDeadline(time.Unix(1, 0)); err != nil {
+					t.Errorf("SetWriteDeadline() error = %v", err)
+				}
+			},
+			check: func(t *testing.T, res *hijackableRecorder) {
+				if !res.deadline.Equal(time.Unix(1, 0)) {
+					t.Errorf("deadline = %v, want it set on the original writer", res.deadline)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.LoggerMiddleware(newFakeNewRelic(t), nopLogger{}, middleware.LoggerConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(t, w)
+			}))
+
+			res := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+			handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+			tt.check(t, res)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/jwtExt"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/redisExt"
+	"boilerplate-service/pkg/util/response"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+
+	rateLimitScriptName = "rate_limit_gcra"
+	rateLimitKeyPrefix  = "ratelimit:"
+)
+
+var errRateLimited = errors.New("too many requests, retry later")
+
+// rateLimitScript implements GCRA. It keeps a single "theoretical arrival time" per key,
+// in milliseconds, and returns {allowed, remaining, retry_after_ms, reset_after_ms}.
+// Durations are returned as strings since Lua numbers are truncated to integers on return.
+const rateLimitScript = `
+redis.replicate_commands()
+
+local key = KEYS[1]
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+local emission_interval = period / rate
+local burst_offset = emission_interval * burst
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+
+local tat = tonumber(redis.call("GET", key))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+local remaining = math.floor(diff / emission_interval)
+
+if remaining < 0 then
+	return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+
+redis.call("SET", key, new_tat, "PX", math.ceil(new_tat - now))
+return {1, remaining, "0", tostring(new_tat - now)}
+`
+
+// RateLimitKeyFunc returns the identity a request is counted against, "" skips the limit
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitPolicy allows Limit requests per Period for each key, with up to Burst of them at once
+type RateLimitPolicy struct {
+	// Name scopes the counters, policies sharing a name share their budget
+	Name   string
+	Limit  int
+	Period time.Duration
+	// Burst defaults to Limit
+	Burst int
+	// Key defaults to RateLimitByClient
+	Key RateLimitKeyFunc
+}
+
+// RateLimiter builds per-route rate limit middlewares sharing one redis client
+type RateLimiter struct {
+	redis  redisExt.IRedisExt
+	logger logger.ILogger
+}
+
+// NewRateLimiter registers the rate limit script on redis.
+//
+// Returns *RateLimiter and an error when the script can't be loaded.
+func NewRateLimiter(redis redisExt.IRedisExt, logger logger.ILogger) (*RateLimiter, error) {
+	if err := redis.RegisterScript(context.Background(), rateLimitScriptName, rateLimitScript); err != nil {
+		return nil, err
+	}
+
+	return &RateLimiter{redis, logger}, nil
+}
+
+// Limit returns a middleware enforcing policy. It sets RateLimit-* headers on every response and
+// Retry-After once the limit is hit. Redis errors let the request through, so an outage of the
+// limiter doesn't take the API down with it.
+func (l *RateLimiter) Limit(policy RateLimitPolicy) func(next http.Handler) http.Handler {
+	if policy.Name == "" || policy.Limit <= 0 || policy.Period <= 0 {
+		panic(fmt.Sprintf("invalid rate limit policy %+v", policy))
+	}
+
+	if policy.Burst <= 0 {
+		policy.Burst = policy.Limit
+	}
+
+	if policy.Key == nil {
+		policy.Key = RateLimitByClient
+	}
+
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int64(math.Ceil(policy.Period.Seconds())))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := policy.Key(r)
+			if identity == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+
+			result, err := l.redis.RunScript(
+				ctx,
+				rateLimitScriptName,
+				[]string{rateLimitKeyPrefix + policy.Name + ":" + identity},
+				policy.Burst,
+				policy.Limit,
+				policy.Period.Milliseconds(),
+			).Slice()
+			if err != nil || len(result) != 4 {
+				l.logger.Warn(ctx, "Rate limit check failed, request allowed", zap.String("policy", policy.Name), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, _ := result[0].(int64)
+			remaining, _ := result[1].(int64)
+			retryAfter := parseRateLimitMillis(result[2])
+			resetAfter := parseRateLimitMillis(result[3])
+
+			w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(policy.Limit))
+			w.Header().Set(HeaderRateLimitRemaining, strconv.FormatInt(remaining, 10))
+			w.Header().Set(HeaderRateLimitReset, strconv.FormatInt(ceilSeconds(resetAfter), 10))
+			w.Header().Set(HeaderRateLimitPolicy, policyHeader)
+
+			if allowed != 1 {
+				w.Header().Set(HeaderRetryAfter, strconv.FormatInt(ceilSeconds(retryAfter), 10))
+				response.SendResponseError(w, response.HttpErrRateLimited, errRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitByIP counts requests per client IP, run it after middleware.RealIP so proxies are accounted for
+func RateLimitByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP replaces RemoteAddr with a bare IP
+		return "ip:" + r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// RateLimitByAPIKey counts requests per key id authenticated by SignatureVerifier, mount it after
+// SignatureVerifier.Verify. Unauthenticated requests are not limited. Keys sent by the caller
+// are never trusted as is, or a client could dodge its limit by making up a new key per request.
+func RateLimitByAPIKey(r *http.Request) string {
+	keyId, _ := r.Context().Value(constant.CtxAPIKeyIdKey).(string)
+	if keyId == "" {
+		return ""
+	}
+
+	return "key:" + keyId
+}
+
+// RateLimitBySubject counts requests per authenticated subject, mount it after AuthMiddleware.
+// Anonymous requests fall back to RateLimitByClient.
+func RateLimitBySubject(r *http.Request) string {
+	if principal := jwtExt.GetPrincipalFromCtx(r.Context()); principal != nil {
+		return "sub:" + principal.Subject
+	}
+
+	return RateLimitByClient(r)
+}
+
+// RateLimitByClient counts requests per authenticated API key, per IP otherwise
+func RateLimitByClient(r *http.Request) string {
+	if identity := RateLimitByAPIKey(r); identity != "" {
+		return identity
+	}
+
+	return RateLimitByIP(r)
+}
+
+func parseRateLimitMillis(value interface{}) float64 {
+	raw, _ := value.(string)
+	millis, _ := strconv.ParseFloat(raw, 64)
+	return millis
+}
+
+func ceilSeconds(millis float64) int64 {
+	if millis <= 0 {
+		return 0
+	}
+
+	return int64(math.Ceil(millis / 1000))
+}
@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/port/http/middleware"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      middleware.RateLimitKeyFunc
+		keyIds   []string
+		apiKeys  []string
+		wantCode []int
+	}{
+		{
+			name:     "Rotating Unauthenticated Keys Share The IP Limit",
+			key:      middleware.RateLimitByClient,
+			apiKeys:  []string{"key-1", "key-2", "key-3"},
+			wantCode: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:     "Rotating Keys Don't Bypass The Subject Fallback",
+			key:      middleware.RateLimitBySubject,
+			apiKeys:  []string{"key-1", "key-2", "key-3"},
+			wantCode: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:     "Authenticated Keys Have Their Own Limit",
+			key:      middleware.RateLimitByClient,
+			keyIds:   []string{"billing", "billing", "orders"},
+			wantCode: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:     "Unauthenticated Requests Skip The API Key Limit",
+			key:      middleware.RateLimitByAPIKey,
+			apiKeys:  []string{"key-1", "key-1", "key-1"},
+			wantCode: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter, err := middleware.NewRateLimiter(newFakeRedis(), nopLogger{})
+			if err != nil {
+				t.Fatalf("NewRateLimiter() error = %v", err)
+			}
+
+			handler := rateLimiter.Limit(middleware.RateLimitPolicy{
+				Name:   "test",
+				Limit:  2,
+				Period: time.Minute,
+				Key:    tt.key,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i, wantCode := range tt.wantCode {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				if i < len(tt.apiKeys) {
+					req.Header.Set("X-Api-Key", tt.apiKeys[i])
+				}
+				if i < len(tt.keyIds) {
+					req = req.WithContext(context.WithValue(req.Context(), constant.CtxAPIKeyIdKey, tt.keyIds[i]))
+				}
+
+				res := httptest.NewRecorder()
+				handler.ServeHTTP(res, req)
+
+				if res.Code != wantCode {
+					t.Errorf("request %d code = %d, want %d", i, res.Code, wantCode)
+				}
+			}
+		})
+	}
+}
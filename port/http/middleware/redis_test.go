@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"boilerplate-service/pkg/redisExt/redisTest"
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis adds a stand-in for the rate limit script to the in-memory redis
+type fakeRedis struct {
+	*redisTest.Redis
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{Redis: redisTest.New(), counts: map[string]int{}}
+}
+
+func (f *fakeRedis) RegisterScript(ctx context.Context, name, src string) error {
+	return nil
+}
+
+// RunScript stands in for the rate limit script with a plain counter per key, ARGV[1] being the burst
+func (f *fakeRedis) RunScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	burst := args[0].(int)
+	if f.counts[keys[0]] >= burst {
+		return redis.NewCmdResult([]interface{}{int64(0), int64(0), "1000", "1000"}, nil)
+	}
+
+	f.counts[keys[0]]++
+	return redis.NewCmdResult([]interface{}{int64(1), int64(burst - f.counts[keys[0]]), "0", "1000"}, nil)
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"boilerplate-service/constant"
+	"boilerplate-service/pkg/logger"
+	"boilerplate-service/pkg/redisExt"
+	httputil "boilerplate-service/pkg/util/http"
+	"boilerplate-service/pkg/util/response"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	signatureNonceKeyPrefix  = "signature-nonce:"
+	defaultSignatureMaxSkew  = 5 * time.Minute
+	defaultSignatureBodySize = 10 << 20
+)
+
+var (
+	errSignatureUnknownKey = errors.New("unknown signature key id")
+	errSignatureReplayed   = errors.New("request signature was already used")
+	errSignatureBodySize   = errors.New("signed request body is too large")
+)
+
+type SignatureConfig struct {
+	// Keys are the shared secrets by caller key id
+	Keys map[string]string
+	// MaxSkew is how far the signed timestamp may be from now, nonces are kept twice as long
+	MaxSkew time.Duration
+	// MaxBodySize caps the body read to compute the digest
+	MaxBodySize int64
+}
+
+// SignatureVerifier authenticates service to service calls signed with httputil.Signer
+type SignatureVerifier struct {
+	redis  redisExt.IRedisExt
+	logger logger.ILogger
+	config SignatureConfig
+}
+
+func NewSignatureVerifier(redis redisExt.IRedisExt, logger logger.ILogger, config SignatureConfig) *SignatureVerifier {
+	if config.MaxSkew == 0 {
+		config.MaxSkew = defaultSignatureMaxSkew
+	}
+
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultSignatureBodySize
+	}
+
+	return &SignatureVerifier{redis, logger, config}
+}
+
+// Verify is a middleware rejecting requests with a missing, stale, invalid or replayed signature.
+// The caller key id is stored in the request context, and as its subject.
+func (v *SignatureVerifier) Verify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		keyId := r.Header.Get(httputil.HeaderSignatureKeyId)
+		secret, ok := v.config.Keys[keyId]
+		if keyId == "" || !ok {
+			response.SendResponseError(w, response.HttpErrUnauthorized, errSignatureUnknownKey)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, v.config.MaxBodySize+1))
+		if err != nil {
+			response.SendResponseError(w, response.HttpErrRequest, err)
+			return
+		}
+		if int64(len(body)) > v.config.MaxBodySize {
+			response.SendResponseError(w, response.HttpErrRequest, errSignatureBodySize)
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		if err := httputil.VerifySignature(r, []byte(secret), body, v.config.MaxSkew); err != nil {
+			v.logger.Info(ctx, "Request signature rejected", zap.String("key_id", keyId), zap.Error(err))
+			response.SendResponseError(w, response.HttpErrUnauthorized, err)
+			return
+		}
+
+		// A nonce outlives the accepted timestamp window, so a replay is either stale or already seen
+		nonceKey := signatureNonceKeyPrefix + keyId + ":" + r.Header.Get(httputil.HeaderSignatureNonce)
+		fresh, err := v.redis.SetNX(ctx, nonceKey, 1, 2*v.config.MaxSkew).Result()
+		if err != nil {
+			v.logger.Error(ctx, "Request signature nonce check failed", zap.String("key_id", keyId), zap.Error(err))
+			response.SendResponseError(w, response.HttpErrInternal, err)
+			return
+		}
+		if !fresh {
+			v.logger.Warn(ctx, "Request signature replayed", zap.String("key_id", keyId))
+			response.SendResponseError(w, response.HttpErrUnauthorized, errSignatureReplayed)
+			return
+		}
+
+		ctx = context.WithValue(ctx, constant.CtxAPIKeyIdKey, keyId)
+		ctx = context.WithValue(ctx, constant.CtxSubjectKey, "service:"+keyId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"boilerplate-service/pkg/traceContext"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// TraceMiddleware continues the caller's trace from traceparent and X-Request-Id, or starts one,
+// and echoes the request id in the response. It replaces chi's middleware.RequestID so chi's
+// request log prints the same id.
+func TraceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace, _ := traceContext.Extract(r.Header)
+
+		ctx := traceContext.NewContext(r.Context(), trace)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, trace.RequestId)
+
+		w.Header().Set(traceContext.HeaderRequestId, trace.RequestId)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
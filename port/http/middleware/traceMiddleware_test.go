@@ -0,0 +1,47 @@
+package middleware_test
+
+import (
+	"boilerplate-service/pkg/traceContext"
+	"boilerplate-service/port/http/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceMiddlewareRequestId(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestId string
+		wantEcho  bool
+	}{
+		{name: "Uuid", requestId: "4bf92f35-77b3-4da6-a3ce-929d0e0e4736", wantEcho: true},
+		{name: "Vendor Format", requestId: "Root=1-5759e988", wantEcho: false},
+		{name: "Opaque Token", requestId: "req_01H:abc.def", wantEcho: true},
+		{name: "Markup", requestId: "<img src=x>", wantEcho: false},
+		{name: "Too Long", requestId: strings.Repeat("a", 200), wantEcho: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged string
+			handler := middleware.TraceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trace, _ := traceContext.FromContext(r.Context())
+				logged = trace.RequestId
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set(traceContext.HeaderRequestId, tt.requestId)
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			echoed := res.Header().Get(traceContext.HeaderRequestId)
+			if (echoed == tt.requestId) != tt.wantEcho {
+				t.Errorf("X-Request-Id = %q, want echoed %v", echoed, tt.wantEcho)
+			}
+			if echoed == "" || logged != echoed {
+				t.Errorf("X-Request-Id = %q, context request id = %q, want the same non empty id", echoed, logged)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package http
 
 import (
+	"boilerplate-service/pkg/jwtExt"
 	"boilerplate-service/pkg/logger"
 	"boilerplate-service/pkg/newRelicExt"
+	"boilerplate-service/pkg/redisExt"
 	"boilerplate-service/port/http/controller"
 	customMiddleware "boilerplate-service/port/http/middleware"
 	"net/http"
@@ -15,17 +17,25 @@ import (
 func HttpRoute(
 	app newRelicExt.INewRelicExt,
 	logger logger.ILogger,
+	loggerConfig customMiddleware.LoggerConfig,
+	idempotencyRedis redisExt.IRedisExt,
+	rateLimiter *customMiddleware.RateLimiter,
+	jwt jwtExt.IJwtExt,
+	signatureVerifier *customMiddleware.SignatureVerifier,
 	v1HealthCheckController controller.V1HealthCheckController,
 ) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.RequestID)
+	r.Use(customMiddleware.TraceMiddleware)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	// Custom Middleware e.g idempotency etc
-	r.Use(customMiddleware.LoggerMiddleware(app, logger))
+	// Bodies of uploads, downloads and other large payloads are kept out of the logs per route
+	// e.g.
+	// loggerConfig.SkipBodyPaths = append(loggerConfig.SkipBodyPaths, "/api/v1/files/*")
+	r.Use(customMiddleware.LoggerMiddleware(app, logger, loggerConfig))
 
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
@@ -33,7 +43,35 @@ func HttpRoute(
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/health-check", v1HealthCheckController.Check)
+		// Rate limits are declared per route, see customMiddleware.RateLimitPolicy
+		r.With(rateLimiter.Limit(customMiddleware.RateLimitPolicy{
+			Name:   "v1-health-check",
+			Limit:  600,
+			Period: time.Minute,
+		})).Get("/health-check", v1HealthCheckController.Check)
+
+		// Authenticated routes, scopes and roles are required per group
+		// e.g.
+		// r.Group(func(r chi.Router) {
+		// 	r.Use(customMiddleware.AuthMiddleware(jwt, logger))
+		// 	r.Use(customMiddleware.RequireScopes("orders:write"))
+		// 	// Idempotency keys are scoped to the subject, so it runs after authentication
+		// 	r.Use(customMiddleware.IdempotencyMiddleware(idempotencyRedis, logger))
+		// 	r.With(rateLimiter.Limit(customMiddleware.RateLimitPolicy{
+		// 		Name:   "v1-create-order",
+		// 		Limit:  30,
+		// 		Period: time.Minute,
+		// 		Key:    customMiddleware.RateLimitBySubject,
+		// 	})).Post("/orders", v1OrderController.Create)
+		// })
+
+		// Internal routes called by other services with httputil.Signer
+		// e.g.
+		// r.Group(func(r chi.Router) {
+		// 	r.Use(signatureVerifier.Verify)
+		// 	r.Use(customMiddleware.IdempotencyMiddleware(idempotencyRedis, logger))
+		// 	r.Post("/internal/orders/sync", v1OrderController.Sync)
+		// })
 	})
 
 	return r